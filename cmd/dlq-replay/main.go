@@ -0,0 +1,56 @@
+// Command dlq-replay moves messages a consumer group parked in its dead-letter topic to the first retry topic
+// of the group, so only that group handles them again. With -to-source they go back to the source topic
+// instead and every group consuming it handles them again, so its handlers must tolerate duplicates.
+//
+//	CONFIG_PATH=config/local.yml go run ./cmd/dlq-replay -group feed-worker -topic post
+//	CONFIG_PATH=config/local.yml go run ./cmd/dlq-replay -group feed-worker -topic post -to-source
+package main
+
+import (
+	"context"
+	"flag"
+	"kirkagram/internal/config"
+	k "kirkagram/internal/kafka"
+	"kirkagram/internal/lib/logger"
+	"log/slog"
+	"os"
+	"os/signal"
+	"syscall"
+)
+
+func main() {
+	var opts k.ReplayOptions
+
+	flag.StringVar(&opts.GroupID, "group", "", "consumer group that failed the messages, e.g. feed-worker")
+	flag.StringVar(&opts.Topic, "topic", "", "source topic name on the broker, e.g. post")
+	flag.IntVar(&opts.Limit, "limit", 0, "replay at most this many messages, 0 replays all")
+	flag.BoolVar(&opts.DryRun, "dry-run", false, "log the messages without replaying them")
+	flag.BoolVar(&opts.ToSource, "to-source", false, "replay to the source topic, read by every group, instead of the retry topic of the group")
+	flag.Parse()
+
+	if opts.GroupID == "" || opts.Topic == "" {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	cfg := config.New()
+
+	log := logger.SetupLogger(cfg.Env)
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	replayed, err := k.ReplayDeadLetters(ctx, cfg, opts, log)
+	if err != nil {
+		log.Error("Replay failed", slog.Int("replayed", replayed), slog.String("error", err.Error()))
+		os.Exit(1)
+	}
+
+	log.Info(
+		"Replay finished",
+		slog.String("topic", k.DeadLetterTopic(opts.Topic, opts.GroupID)),
+		slog.Int("replayed", replayed),
+		slog.Bool("dryRun", opts.DryRun),
+		slog.Bool("toSource", opts.ToSource),
+	)
+}
@@ -0,0 +1,42 @@
+package main
+
+import (
+	"context"
+	"kirkagram/internal/config"
+	k "kirkagram/internal/kafka"
+	"kirkagram/internal/lib/logger"
+	"kirkagram/internal/service"
+	"kirkagram/internal/storage"
+	"kirkagram/internal/storage/psgr"
+	"kirkagram/internal/transport/consumer"
+	"os"
+	"os/signal"
+	"syscall"
+)
+
+const consumerGroup = "feed-worker"
+
+func main() {
+	cfg := config.New()
+
+	log := logger.SetupLogger(cfg.Env)
+
+	db := storage.New(cfg, log)
+
+	log.Info("Starting feed worker")
+
+	timelineRepo := psgr.NewTimelineStorage(db)
+	timelineService := service.NewTimelineService(timelineRepo, log)
+
+	c := k.NewConsumer(cfg, consumerGroup, log)
+	consumer.RegisterFeed(c, timelineService)
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	if err := c.Run(ctx); err != nil {
+		panic(err)
+	}
+
+	log.Info("Feed worker stopped")
+}
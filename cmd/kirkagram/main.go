@@ -13,61 +13,116 @@
 //	@host		localhost:8082
 //	@BasePath	/api
 
+//	@securityDefinitions.apikey	BearerAuth
+//	@in							header
+//	@name						Authorization
+
 package main
 
 import (
+	"context"
+	"database/sql"
+	"fmt"
 	httpSwagger "github.com/swaggo/http-swagger"
 	_ "kirkagram/docs"
 	"kirkagram/internal/config"
 	k "kirkagram/internal/kafka"
-	"kirkagram/internal/lib/logger/handlers/slogpretty"
+	"kirkagram/internal/lib/logger"
 	"kirkagram/internal/service"
 	"kirkagram/internal/storage"
+	"kirkagram/internal/storage/disk"
+	"kirkagram/internal/storage/memory"
+	"kirkagram/internal/storage/migrate"
 	"kirkagram/internal/storage/psgr"
 	S3Storage "kirkagram/internal/storage/s3"
+	"kirkagram/internal/storage/sqlite"
+	"kirkagram/internal/transport/consumer"
 	"kirkagram/internal/transport/rest"
 	"kirkagram/internal/transport/rest/handlers"
+	"kirkagram/migrations"
 	"log/slog"
 	"net/http"
 	"os"
-)
-
-const (
-	envLocal = "local"
-	envProd  = "prod"
-	envDev   = "dev"
+	"os/signal"
+	"syscall"
 )
 
 func main() {
 	cfg := config.New()
-	db := storage.New(cfg)
-	S3Client := storage.NewS3Client()
 
-	log := setupLogger(cfg.Env)
+	log := logger.SetupLogger(cfg.Env)
 
-	log.Info("Starting application")
-	log.Info("Current address", slog.String("port", cfg.HttpServe.Address))
+	var db *sql.DB
+	var repos repositories
 
-	userRepo := psgr.NewUserStorage(db)
-	postRepo := psgr.NewPostStorage(db)
-	likeRepo := psgr.NewLikeStorage(db)
-	followRepo := psgr.NewFollowStorage(db)
-	s3Repo := S3Storage.NewUserS3Storage(S3Client)
-	producer := k.NewProducer(cfg, log)
+	if cfg.Database.Driver == "sqlite" {
+		db = sqlite.New(cfg, log)
+		repos = sqliteRepositories(db)
+	} else {
+		db = storage.New(cfg, log)
+		repos = psgrRepositories(db)
+
+		// The API refuses to start against a schema without the migrations it depends on.
+		migrator, err := migrate.New(db, migrations.FS, log)
+		if err != nil {
+			panic(err)
+		}
+		if err := migrator.Check(context.Background()); err != nil {
+			panic(fmt.Errorf("%w, run cmd/migrate up", err))
+		}
+	}
+
+	log.Info("Starting application", slog.String("driver", cfg.Database.Driver), slog.String("photos", cfg.Photos.Driver))
+	log.Info("Current address", slog.String("port", cfg.HttpServe.Address))
 
-	userService := service.NewUserService(log, userRepo)
-	postService := service.NewPostService(postRepo, *producer, log)
-	likeService := service.NewLikeService(likeRepo, *producer, log)
-	followService := service.NewFollowService(followRepo, *producer, log)
-	photoService := service.NewPhotoService(s3Repo, log)
+	authService := service.NewAuthService(repos.users, repos.tokens, log, cfg.Auth.Secret, cfg.Auth.AccessTokenTTL, cfg.Auth.RefreshTokenTTL)
+	userService := service.NewUserService(log, repos.users)
+	postService := service.NewPostService(repos.posts, log)
+	likeService := service.NewLikeService(repos.likes, log)
+	followService := service.NewFollowService(repos.follows, log)
+	photoService := service.NewPhotoService(newPhotoStorage(cfg.Photos), cfg.Photos.MaxDimension, log)
+	notificationStream := service.NewNotificationStream(log)
 
+	authHandler := handlers.NewAuthHandler(authService, log)
 	userHandler := handlers.NewUserHandler(userService, log)
-	photoHandler := handlers.NewPhotoHandler(userService, photoService, log)
-	postHandler := handlers.NewPostHandler(postService, photoService, log)
+	photoHandler := handlers.NewPhotoHandler(userService, photoService, cfg.Photos.MaxUploadSize, log)
+	postHandler := handlers.NewPostHandler(postService, photoService, cfg.Photos.MaxUploadSize, log)
 	LikeHandler := handlers.NewLikeHandler(likeService, log)
 	followHandler := handlers.NewFollowHandler(followService, log)
 
-	handler := rest.NewHandler(log, userHandler, photoHandler, postHandler, LikeHandler, followHandler)
+	// Comments, the feed and notifications rely on Postgres and the event pipeline,
+	// the SQLite backend serves the API without them.
+	var commentHandler *handlers.CommentHandler
+	var feedHandler *handlers.FeedHandler
+	var notificationHandler *handlers.NotificationHandler
+
+	stopEvents := func(ctx context.Context) {}
+
+	if cfg.Database.Driver != "sqlite" {
+		commentService := service.NewCommentService(psgr.NewCommentStorage(db), repos.posts, log)
+		feedService := service.NewFeedService(psgr.NewFeedStorage(db), log)
+		notificationService := service.NewNotificationService(psgr.NewNotificationStorage(db), log)
+
+		commentHandler = handlers.NewCommentHandler(commentService, log)
+		feedHandler = handlers.NewFeedHandler(feedService, log)
+		notificationHandler = handlers.NewNotificationHandler(notificationService, notificationStream, log)
+
+		if cfg.Kafka.Mode == "memory" {
+			log.Info("Kafka is disabled, events are handled in process")
+
+			stopEvents = runInMemoryEvents(db, notificationStream, log)
+		} else {
+			stopEvents = runNotificationStream(cfg, notificationStream, log)
+		}
+	}
+
+	timeouts := rest.Timeouts{
+		Request:  cfg.HttpServe.Timeout,
+		Write:    cfg.HttpServe.WriteTimeout,
+		Transfer: cfg.HttpServe.TransferTimeout,
+	}
+
+	handler := rest.NewHandler(log, cfg.Auth.Secret, timeouts, authHandler, userHandler, photoHandler, postHandler, LikeHandler, followHandler, commentHandler, feedHandler, notificationHandler)
 
 	router := handler.InitRouter()
 
@@ -77,39 +132,182 @@ func main() {
 	srv := &http.Server{
 		Addr:    cfg.HttpServe.Address,
 		Handler: router,
+		// Read and write deadlines are set per route, see rest.Timeouts.
+		ReadHeaderTimeout: cfg.HttpServe.Timeout,
+		IdleTimeout:       cfg.HttpServe.IdleTimeout,
+	}
+
+	// Notification streams never finish on their own, so they are closed as soon as the shutdown begins.
+	srv.RegisterOnShutdown(notificationStream.Close)
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	serveErr := make(chan error, 1)
+
+	go func() {
+		log.Info("SERVER STARTED AT", slog.String("address", cfg.HttpServe.Address))
+
+		serveErr <- srv.ListenAndServe()
+	}()
+
+	exitCode := 0
+
+	select {
+	case <-ctx.Done():
+		log.Info("Shutting down", slog.Duration("drain", cfg.HttpServe.ShutdownTimeout))
+	case err := <-serveErr:
+		log.Error("Server failed", slog.String("error", err.Error()))
+
+		exitCode = 1
+	}
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), cfg.HttpServe.ShutdownTimeout)
+	defer cancel()
+
+	// In-flight requests are finished first, they may still write events to the outbox.
+	if err := srv.Shutdown(shutdownCtx); err != nil {
+		log.Error("Unable to finish in-flight requests", slog.String("error", err.Error()))
+	}
+
+	stopEvents(shutdownCtx)
+
+	if err := db.Close(); err != nil {
+		log.Error("Unable to close database", slog.String("error", err.Error()))
 	}
 
-	log.Info("SERVER STARTED AT", slog.String("address", cfg.HttpServe.Address))
+	log.Info("Server stopped")
+
+	if exitCode != 0 {
+		os.Exit(exitCode)
+	}
+}
 
-	if err := srv.ListenAndServe(); err != nil {
-		panic(err)
+// newPhotoStorage picks the photo storage by photos.driver.
+func newPhotoStorage(cfg config.Photos) service.PhotoService {
+	switch cfg.Driver {
+	case "fs":
+		return disk.NewPhotoStorage(cfg.Path)
+	case "memory":
+		return memory.NewPhotoStorage()
+	default:
+		return S3Storage.NewUserS3Storage(storage.NewS3Client(cfg.S3), cfg.S3.Bucket)
 	}
+}
 
+// repositories are the storages shared by both database drivers.
+type repositories struct {
+	users interface {
+		service.UserService
+		service.AuthUserService
+	}
+	tokens  service.TokenService
+	posts   service.PostService
+	likes   service.LikeService
+	follows service.FollowService
 }
 
-func setupLogger(env string) *slog.Logger {
-	var log *slog.Logger
-
-	switch env {
-	case envLocal:
-		log = setupPrettySlog()
-	case envDev:
-		log = slog.New(
-			slog.NewJSONHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelDebug}),
-		)
-	case envProd:
-		log = slog.New(
-			slog.NewJSONHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelInfo}),
-		)
+func psgrRepositories(db *sql.DB) repositories {
+	return repositories{
+		users:   psgr.NewUserStorage(db),
+		tokens:  psgr.NewTokenStorage(db),
+		posts:   psgr.NewPostStorage(db),
+		likes:   psgr.NewLikeStorage(db),
+		follows: psgr.NewFollowStorage(db),
 	}
+}
 
-	return log
+func sqliteRepositories(db *sql.DB) repositories {
+	return repositories{
+		users:   sqlite.NewUserStorage(db),
+		tokens:  sqlite.NewTokenStorage(db),
+		posts:   sqlite.NewPostStorage(db),
+		likes:   sqlite.NewLikeStorage(db),
+		follows: sqlite.NewFollowStorage(db),
+	}
 }
 
-func setupPrettySlog() *slog.Logger {
-	opts := slogpretty.PrettyHandlerOptions{SlogOpts: &slog.HandlerOptions{Level: slog.LevelDebug}}
+// runInMemoryEvents runs the outbox relay and the event consumers inside the API process,
+// so the whole application works without a Kafka broker. The returned function stops them.
+func runInMemoryEvents(db *sql.DB, notificationStream *service.NotificationStream, log *slog.Logger) func(ctx context.Context) {
+	bus := k.NewBus(log)
+
+	timelineService := service.NewTimelineService(psgr.NewTimelineStorage(db), log)
+	consumer.RegisterFeed(bus.Group("feed-worker"), timelineService)
+
+	statsService := service.NewStatsService(psgr.NewStatsStorage(db), log)
+	consumer.RegisterStats(bus.Group("event-processor"), statsService)
+
+	notifierService := service.NewNotifierService(psgr.NewNotificationStorage(db), psgr.NewPostStorage(db), psgr.NewCommentStorage(db), log)
+	consumer.RegisterNotifications(bus.Group("notifier"), notifierService)
+
+	consumer.RegisterNotificationStream(bus.Group("notification-stream"), notificationStream)
+
+	relay := service.NewRelayService(psgr.NewOutboxStorage(db), bus, log)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	relayCtx, stopRelay := context.WithCancel(ctx)
+
+	busDone := make(chan struct{})
+	relayDone := make(chan struct{})
+
+	go func() {
+		defer close(busDone)
 
-	handler := opts.NewPrettyHandler(os.Stdout)
+		bus.Run(ctx)
+	}()
 
-	return slog.New(handler)
+	go func() {
+		defer close(relayDone)
+
+		relay.Run(relayCtx)
+	}()
+
+	// The relay stops first, then the bus handles the events it already took from the outbox.
+	// Events left in the outbox are published after restart.
+	return func(shutdownCtx context.Context) {
+		defer cancel()
+
+		stopRelay()
+		<-relayDone
+
+		bus.Close()
+
+		select {
+		case <-busDone:
+		case <-shutdownCtx.Done():
+			log.Warn("Shutdown timeout exceeded, queued events are dropped")
+
+			cancel()
+			<-busDone
+		}
+	}
+}
+
+// runNotificationStream consumes notification changes to wake the streams open on this instance.
+// The returned function stops the consumer.
+func runNotificationStream(cfg *config.Config, notificationStream *service.NotificationStream, log *slog.Logger) func(ctx context.Context) {
+	c := k.NewBroadcastConsumer(cfg, "notification-stream", log)
+	consumer.RegisterNotificationStream(c, notificationStream)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+
+	go func() {
+		defer close(done)
+
+		if err := c.Run(ctx); err != nil {
+			log.Error("Notification stream consumer stopped", slog.String("error", err.Error()))
+		}
+	}()
+
+	return func(shutdownCtx context.Context) {
+		cancel()
+
+		select {
+		case <-done:
+		case <-shutdownCtx.Done():
+			log.Warn("Shutdown timeout exceeded, notification stream consumer is left running")
+		}
+	}
 }
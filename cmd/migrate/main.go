@@ -0,0 +1,145 @@
+// Command migrate applies the SQL migrations embedded from the migrations directory.
+//
+//	CONFIG_PATH=config/local.yml go run ./cmd/migrate up
+//	CONFIG_PATH=config/local.yml go run ./cmd/migrate down 1
+//	CONFIG_PATH=config/local.yml go run ./cmd/migrate status
+//
+// A database whose schema was applied by hand is adopted with baseline, which records the migrations
+// up to the given version as applied without running them:
+//
+//	CONFIG_PATH=config/local.yml go run ./cmd/migrate baseline 12
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"kirkagram/internal/config"
+	"kirkagram/internal/lib/logger"
+	"kirkagram/internal/storage"
+	"kirkagram/internal/storage/migrate"
+	"kirkagram/migrations"
+	"log/slog"
+	"os"
+	"os/signal"
+	"strconv"
+	"syscall"
+	"text/tabwriter"
+	"time"
+)
+
+func main() {
+	flag.Usage = func() {
+		fmt.Fprintln(flag.CommandLine.Output(), "usage: migrate up | down N | status | baseline VERSION")
+	}
+	flag.Parse()
+
+	command, n, ok := parseArgs(flag.Args())
+	if !ok {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	cfg := config.New()
+
+	log := logger.SetupLogger(cfg.Env)
+
+	// The SQLite database gets its schema when the API opens it.
+	if cfg.Database.Driver != "postgres" {
+		log.Error("Migrations are only run against Postgres", slog.String("driver", cfg.Database.Driver))
+		os.Exit(2)
+	}
+
+	db := storage.New(cfg, log)
+	defer db.Close()
+
+	migrator, err := migrate.New(db, migrations.FS, log)
+	if err != nil {
+		log.Error("Unable to load migrations", slog.String("error", err.Error()))
+		os.Exit(1)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	switch command {
+	case "up":
+		num, err := migrator.Up(ctx)
+		if err != nil {
+			log.Error("Migration failed", slog.Int("applied", num), slog.String("error", err.Error()))
+			os.Exit(1)
+		}
+
+		log.Info("Schema is up to date", slog.Int("applied", num))
+	case "down":
+		num, err := migrator.Down(ctx, n)
+		if err != nil {
+			log.Error("Rollback failed", slog.Int("reverted", num), slog.String("error", err.Error()))
+			os.Exit(1)
+		}
+
+		log.Info("Rollback finished", slog.Int("reverted", num))
+	case "baseline":
+		num, err := migrator.Baseline(ctx, n)
+		if err != nil {
+			log.Error("Baseline failed", slog.String("error", err.Error()))
+			os.Exit(1)
+		}
+
+		log.Info("Baseline recorded", slog.Int("version", n), slog.Int("recorded", num))
+	case "status":
+		statuses, err := migrator.Status(ctx)
+		if err != nil {
+			log.Error("Unable to get status", slog.String("error", err.Error()))
+			os.Exit(1)
+		}
+
+		printStatus(statuses)
+	}
+}
+
+// parseArgs returns the command and its numeric argument, if the command takes one.
+func parseArgs(args []string) (string, int, bool) {
+	if len(args) == 0 {
+		return "", 0, false
+	}
+
+	switch args[0] {
+	case "up", "status":
+		return args[0], 0, len(args) == 1
+	case "down", "baseline":
+		if len(args) != 2 {
+			return "", 0, false
+		}
+
+		n, err := strconv.Atoi(args[1])
+		if err != nil || n < 1 {
+			return "", 0, false
+		}
+
+		return args[0], n, true
+	}
+
+	return "", 0, false
+}
+
+func printStatus(statuses []migrate.Status) {
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	defer w.Flush()
+
+	fmt.Fprintln(w, "VERSION\tNAME\tAPPLIED AT")
+
+	for _, status := range statuses {
+		name := status.Name
+		if name == "" {
+			name = "(unknown to this binary)"
+		}
+
+		appliedAt := "pending"
+		if status.AppliedAt != nil {
+			appliedAt = status.AppliedAt.Format(time.DateTime)
+		}
+
+		fmt.Fprintf(w, "%d\t%s\t%s\n", status.Version, name, appliedAt)
+	}
+}
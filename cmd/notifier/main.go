@@ -0,0 +1,44 @@
+package main
+
+import (
+	"context"
+	"kirkagram/internal/config"
+	k "kirkagram/internal/kafka"
+	"kirkagram/internal/lib/logger"
+	"kirkagram/internal/service"
+	"kirkagram/internal/storage"
+	"kirkagram/internal/storage/psgr"
+	"kirkagram/internal/transport/consumer"
+	"os"
+	"os/signal"
+	"syscall"
+)
+
+const consumerGroup = "notifier"
+
+func main() {
+	cfg := config.New()
+
+	log := logger.SetupLogger(cfg.Env)
+
+	db := storage.New(cfg, log)
+
+	log.Info("Starting notifier")
+
+	notificationRepo := psgr.NewNotificationStorage(db)
+	postRepo := psgr.NewPostStorage(db)
+	commentRepo := psgr.NewCommentStorage(db)
+	notifierService := service.NewNotifierService(notificationRepo, postRepo, commentRepo, log)
+
+	c := k.NewConsumer(cfg, consumerGroup, log)
+	consumer.RegisterNotifications(c, notifierService)
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	if err := c.Run(ctx); err != nil {
+		panic(err)
+	}
+
+	log.Info("Notifier stopped")
+}
@@ -0,0 +1,37 @@
+package main
+
+import (
+	"context"
+	"kirkagram/internal/config"
+	k "kirkagram/internal/kafka"
+	"kirkagram/internal/lib/logger"
+	"kirkagram/internal/service"
+	"kirkagram/internal/storage"
+	"kirkagram/internal/storage/psgr"
+	"os"
+	"os/signal"
+	"syscall"
+)
+
+func main() {
+	cfg := config.New()
+
+	log := logger.SetupLogger(cfg.Env)
+
+	db := storage.New(cfg, log)
+
+	log.Info("Starting outbox relay")
+
+	producer := k.NewProducer(cfg, log)
+	defer producer.Close()
+
+	outboxRepo := psgr.NewOutboxStorage(db)
+	relay := service.NewRelayService(outboxRepo, producer, log)
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	relay.Run(ctx)
+
+	log.Info("Outbox relay stopped")
+}
@@ -1 +1,42 @@
 package main
+
+import (
+	"context"
+	"kirkagram/internal/config"
+	k "kirkagram/internal/kafka"
+	"kirkagram/internal/lib/logger"
+	"kirkagram/internal/service"
+	"kirkagram/internal/storage"
+	"kirkagram/internal/storage/psgr"
+	"kirkagram/internal/transport/consumer"
+	"os"
+	"os/signal"
+	"syscall"
+)
+
+const consumerGroup = "event-processor"
+
+func main() {
+	cfg := config.New()
+
+	log := logger.SetupLogger(cfg.Env)
+
+	db := storage.New(cfg, log)
+
+	log.Info("Starting event processor")
+
+	statsRepo := psgr.NewStatsStorage(db)
+	statsService := service.NewStatsService(statsRepo, log)
+
+	c := k.NewConsumer(cfg, consumerGroup, log)
+	consumer.RegisterStats(c, statsService)
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	if err := c.Run(ctx); err != nil {
+		panic(err)
+	}
+
+	log.Info("Event processor stopped")
+}
@@ -1,6 +1,7 @@
 package config
 
 import (
+	"errors"
 	"fmt"
 	"github.com/ilyakaznacheev/cleanenv"
 	"os"
@@ -9,21 +10,106 @@ import (
 
 type Config struct {
 	Env         string    `yaml:"env" env-required:"true"`
-	StoragePath string    `yaml:"storage_path" env-required:"true"`
+	StoragePath string    `yaml:"storage_path" env:"STORAGE_PATH" env-required:"true"` // файл базы SQLite
 	HttpServe   HttpServe `yaml:"http_serve" env-required:"true"`
+	Database    Database  `yaml:"database" env-required:"true"`
 	Kafka       Kafka     `yaml:"kafka" env-required:"true"`
+	Photos      Photos    `yaml:"photos"`
+	Auth        Auth      `yaml:"auth" env-required:"true"`
+}
+
+type Auth struct {
+	Secret          string        `yaml:"secret" env:"AUTH_SECRET" env-required:"true"`
+	AccessTokenTTL  time.Duration `yaml:"access_token_ttl" env-default:"15m"`
+	RefreshTokenTTL time.Duration `yaml:"refresh_token_ttl" env-default:"720h"`
 }
 
 type Kafka struct {
-	Address   string `yaml:"address"`
-	TopicLike string `yaml:"topic_like"`
-	TopicPost string `yaml:"topic_post"`
+	Mode         string        `yaml:"mode" env:"KAFKA_MODE" env-default:"kafka"` // kafka или memory: события внутри одного процесса, без брокера
+	Brokers      []string      `yaml:"brokers" env:"KAFKA_BROKERS" env-separator:","`
+	ClientID     string        `yaml:"client_id" env:"KAFKA_CLIENT_ID" env-default:"kirkagram"`
+	Acks         string        `yaml:"acks" env-default:"all"`         // none, leader или all
+	Compression  string        `yaml:"compression" env-default:"none"` // none, gzip, snappy, lz4 или zstd
+	Retries      int           `yaml:"retries" env-default:"5"`
+	RetryBackoff time.Duration `yaml:"retry_backoff" env-default:"100ms"`
+	Topics       KafkaTopics   `yaml:"topics"`
+	Retry        KafkaRetry    `yaml:"retry"`
+	TLS          KafkaTLS      `yaml:"tls"`
+	SASL         KafkaSASL     `yaml:"sasl"`
+}
+
+// KafkaTopics maps the topics events are grouped by to the topic names of the environment.
+type KafkaTopics struct {
+	Post         string `yaml:"post" env-default:"post"`
+	Like         string `yaml:"like" env-default:"like"`
+	Follow       string `yaml:"follow" env-default:"follow"`
+	Comment      string `yaml:"comment" env-default:"comment"`
+	User         string `yaml:"user" env-default:"user"`
+	Notification string `yaml:"notification" env-default:"notification"`
+}
+
+// KafkaRetry sets how consumers retry failed messages. Every delay is a retry topic, a message that
+// fails after the last one is moved to the dead-letter topic.
+type KafkaRetry struct {
+	Delays []time.Duration `yaml:"delays" env:"KAFKA_RETRY_DELAYS" env-separator:"," env-default:"10s,1m,10m"`
+}
+
+type KafkaTLS struct {
+	Enabled            bool   `yaml:"enabled" env:"KAFKA_TLS_ENABLED"`
+	CAFile             string `yaml:"ca_file" env:"KAFKA_TLS_CA_FILE"`
+	CertFile           string `yaml:"cert_file" env:"KAFKA_TLS_CERT_FILE"`
+	KeyFile            string `yaml:"key_file" env:"KAFKA_TLS_KEY_FILE"`
+	InsecureSkipVerify bool   `yaml:"insecure_skip_verify"`
+}
+
+type KafkaSASL struct {
+	Enabled   bool   `yaml:"enabled" env:"KAFKA_SASL_ENABLED"`
+	Mechanism string `yaml:"mechanism" env:"KAFKA_SASL_MECHANISM" env-default:"PLAIN"` // PLAIN, SCRAM-SHA-256 или SCRAM-SHA-512
+	User      string `yaml:"user" env:"KAFKA_SASL_USER"`
+	Password  string `yaml:"password" env:"KAFKA_SASL_PASSWORD"`
+}
+
+type Database struct {
+	Driver          string        `yaml:"driver" env:"DB_DRIVER" env-default:"postgres"` // postgres или sqlite, SQLite хранит базу в storage_path
+	Host            string        `yaml:"host" env:"DB_HOST" env-default:"localhost"`
+	Port            int           `yaml:"port" env:"DB_PORT" env-default:"5432"`
+	User            string        `yaml:"user" env:"DB_USER"`
+	Password        string        `yaml:"password" env:"DB_PASSWORD"`
+	Name            string        `yaml:"name" env:"DB_NAME"`
+	SSLMode         string        `yaml:"ssl_mode" env:"DB_SSL_MODE" env-default:"disable"` // disable, require, verify-ca или verify-full
+	MaxOpenConns    int           `yaml:"max_open_conns" env:"DB_MAX_OPEN_CONNS" env-default:"25"`
+	MaxIdleConns    int           `yaml:"max_idle_conns" env:"DB_MAX_IDLE_CONNS" env-default:"25"`
+	ConnMaxLifetime time.Duration `yaml:"conn_max_lifetime" env:"DB_CONN_MAX_LIFETIME" env-default:"30m"`
+	ConnMaxIdleTime time.Duration `yaml:"conn_max_idle_time" env:"DB_CONN_MAX_IDLE_TIME" env-default:"5m"`
+	ConnectTimeout  time.Duration `yaml:"connect_timeout" env:"DB_CONNECT_TIMEOUT" env-default:"5s"`
+	ConnectAttempts int           `yaml:"connect_attempts" env:"DB_CONNECT_ATTEMPTS" env-default:"10"` // сколько раз пробовать подключиться при старте
+	ConnectBackoff  time.Duration `yaml:"connect_backoff" env:"DB_CONNECT_BACKOFF" env-default:"500ms"`
+}
+
+type Photos struct {
+	Driver        string   `yaml:"driver" env:"PHOTOS_DRIVER" env-default:"s3"`           // s3, fs или memory: фото пропадают при перезапуске
+	Path          string   `yaml:"path" env:"PHOTOS_PATH" env-default:"./storage/photos"` // каталог для fs
+	S3            PhotosS3 `yaml:"s3"`
+	MaxDimension  int      `yaml:"max_dimension" env:"PHOTOS_MAX_DIMENSION" env-default:"6000"`         // наибольшая ширина и высота загружаемого фото в пикселях
+	MaxUploadSize int64    `yaml:"max_upload_size" env:"PHOTOS_MAX_UPLOAD_SIZE" env-default:"20971520"` // наибольший размер тела запроса с фото в байтах
+}
+
+type PhotosS3 struct {
+	Bucket          string `yaml:"bucket" env:"S3_BUCKET" env-default:"kirkagram"`
+	Region          string `yaml:"region" env:"S3_REGION"`     // пусто: регион из окружения или ~/.aws
+	Endpoint        string `yaml:"endpoint" env:"S3_ENDPOINT"` // например http://localhost:9000 для MinIO
+	UsePathStyle    bool   `yaml:"use_path_style" env:"S3_USE_PATH_STYLE"`
+	AccessKeyID     string `yaml:"access_key_id" env:"S3_ACCESS_KEY_ID"` // пусто: ключи из окружения или ~/.aws
+	SecretAccessKey string `yaml:"secret_access_key" env:"S3_SECRET_ACCESS_KEY"`
 }
 
 type HttpServe struct {
-	Address     string        `yaml:"address" env-default:"8080"`
-	Timeout     time.Duration `yaml:"timeout" env-default:"5s"`
-	IdleTimeout time.Duration `yaml:"idle_timeout" env-default:"5s"`
+	Address         string        `yaml:"address" env-default:"8080"`
+	Timeout         time.Duration `yaml:"timeout" env-default:"5s"`          // чтение запроса и обработка запроса
+	WriteTimeout    time.Duration `yaml:"write_timeout" env-default:"10s"`   // должен быть больше timeout
+	TransferTimeout time.Duration `yaml:"transfer_timeout" env-default:"5m"` // загрузка и отдача фото
+	IdleTimeout     time.Duration `yaml:"idle_timeout" env-default:"5s"`
+	ShutdownTimeout time.Duration `yaml:"shutdown_timeout" env-default:"15s"` // сколько ждать завершения запросов при остановке
 }
 
 func New() *Config {
@@ -39,18 +125,211 @@ func New() *Config {
 		panic(err)
 	}
 
+	if err := cfg.Database.Validate(); err != nil {
+		panic(err)
+	}
+
+	if err := cfg.Kafka.Validate(); err != nil {
+		panic(err)
+	}
+
+	if err := cfg.Photos.Validate(); err != nil {
+		panic(err)
+	}
+
 	return &Config{
 		Env:         cfg.Env,
 		StoragePath: cfg.StoragePath,
 		HttpServe: HttpServe{
-			Address:     cfg.HttpServe.Address,
-			Timeout:     cfg.HttpServe.Timeout,
-			IdleTimeout: cfg.HttpServe.IdleTimeout,
+			Address:         cfg.HttpServe.Address,
+			Timeout:         cfg.HttpServe.Timeout,
+			WriteTimeout:    cfg.HttpServe.WriteTimeout,
+			TransferTimeout: cfg.HttpServe.TransferTimeout,
+			IdleTimeout:     cfg.HttpServe.IdleTimeout,
+			ShutdownTimeout: cfg.HttpServe.ShutdownTimeout,
 		},
+		Database: cfg.Database,
 		Kafka: Kafka{
-			Address:   cfg.Kafka.Address,
-			TopicLike: cfg.Kafka.TopicLike,
-			TopicPost: cfg.Kafka.TopicPost,
+			Mode:         cfg.Kafka.Mode,
+			Brokers:      cfg.Kafka.Brokers,
+			ClientID:     cfg.Kafka.ClientID,
+			Acks:         cfg.Kafka.Acks,
+			Compression:  cfg.Kafka.Compression,
+			Retries:      cfg.Kafka.Retries,
+			RetryBackoff: cfg.Kafka.RetryBackoff,
+			Topics:       cfg.Kafka.Topics,
+			Retry:        cfg.Kafka.Retry,
+			TLS:          cfg.Kafka.TLS,
+			SASL:         cfg.Kafka.SASL,
 		},
+		Photos: cfg.Photos,
+		Auth: Auth{
+			Secret:          cfg.Auth.Secret,
+			AccessTokenTTL:  cfg.Auth.AccessTokenTTL,
+			RefreshTokenTTL: cfg.Auth.RefreshTokenTTL,
+		},
+	}
+}
+
+// Validate checks the database settings of the chosen driver.
+func (d Database) Validate() error {
+	const op = "config.Database.Validate"
+
+	switch d.Driver {
+	case "sqlite":
+		return nil
+	case "postgres":
+	default:
+		return fmt.Errorf("%s: unknown driver %q", op, d.Driver)
+	}
+
+	var errs []error
+
+	if d.User == "" {
+		errs = append(errs, errors.New("user is required"))
+	}
+
+	if d.Name == "" {
+		errs = append(errs, errors.New("name is required"))
+	}
+
+	if d.ConnectAttempts < 1 {
+		errs = append(errs, errors.New("connect_attempts must be at least 1"))
+	}
+
+	if err := errors.Join(errs...); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+// Validate checks the settings of the chosen photo storage.
+func (p Photos) Validate() error {
+	const op = "config.Photos.Validate"
+
+	if p.MaxDimension < 1 {
+		return fmt.Errorf("%s: max_dimension must be at least 1", op)
 	}
+
+	if p.MaxUploadSize < 1 {
+		return fmt.Errorf("%s: max_upload_size must be at least 1", op)
+	}
+
+	switch p.Driver {
+	case "memory":
+		return nil
+	case "fs":
+		if p.Path == "" {
+			return fmt.Errorf("%s: path is required", op)
+		}
+
+		return nil
+	case "s3":
+	default:
+		return fmt.Errorf("%s: unknown driver %q", op, p.Driver)
+	}
+
+	var errs []error
+
+	if p.S3.Bucket == "" {
+		errs = append(errs, errors.New("s3.bucket is required"))
+	}
+
+	if (p.S3.AccessKeyID == "") != (p.S3.SecretAccessKey == "") {
+		errs = append(errs, errors.New("s3.access_key_id and s3.secret_access_key are set together"))
+	}
+
+	if err := errors.Join(errs...); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+// Validate checks the Kafka settings, so a misconfigured deployment fails at startup instead of on the first event.
+func (k Kafka) Validate() error {
+	const op = "config.Kafka.Validate"
+
+	var errs []error
+
+	switch k.Mode {
+	case "memory":
+		return nil
+	case "kafka":
+	default:
+		return fmt.Errorf("%s: unknown mode %q", op, k.Mode)
+	}
+
+	if len(k.Brokers) == 0 {
+		errs = append(errs, errors.New("at least one broker is required"))
+	}
+
+	for _, broker := range k.Brokers {
+		if broker == "" {
+			errs = append(errs, errors.New("broker address is empty"))
+		}
+	}
+
+	if k.ClientID == "" {
+		errs = append(errs, errors.New("client_id is empty"))
+	}
+
+	switch k.Acks {
+	case "none", "leader", "all":
+	default:
+		errs = append(errs, fmt.Errorf("unknown acks %q", k.Acks))
+	}
+
+	switch k.Compression {
+	case "none", "gzip", "snappy", "lz4", "zstd":
+	default:
+		errs = append(errs, fmt.Errorf("unknown compression %q", k.Compression))
+	}
+
+	if k.Retries < 0 {
+		errs = append(errs, errors.New("retries must not be negative"))
+	}
+
+	topics := map[string]string{
+		"post":         k.Topics.Post,
+		"like":         k.Topics.Like,
+		"follow":       k.Topics.Follow,
+		"comment":      k.Topics.Comment,
+		"user":         k.Topics.User,
+		"notification": k.Topics.Notification,
+	}
+	for name, topic := range topics {
+		if topic == "" {
+			errs = append(errs, fmt.Errorf("topic %s is empty", name))
+		}
+	}
+
+	for _, delay := range k.Retry.Delays {
+		if delay <= 0 {
+			errs = append(errs, fmt.Errorf("retry delay %s must be positive", delay))
+		}
+	}
+
+	if k.TLS.Enabled && (k.TLS.CertFile == "") != (k.TLS.KeyFile == "") {
+		errs = append(errs, errors.New("tls cert_file and key_file must be set together"))
+	}
+
+	if k.SASL.Enabled {
+		switch k.SASL.Mechanism {
+		case "PLAIN", "SCRAM-SHA-256", "SCRAM-SHA-512":
+		default:
+			errs = append(errs, fmt.Errorf("unknown sasl mechanism %q", k.SASL.Mechanism))
+		}
+
+		if k.SASL.User == "" || k.SASL.Password == "" {
+			errs = append(errs, errors.New("sasl user and password are required"))
+		}
+	}
+
+	if err := errors.Join(errs...); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
 }
@@ -0,0 +1,172 @@
+package kafka
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/IBM/sarama"
+	"kirkagram/internal/config"
+	"log/slog"
+	"sync"
+	"time"
+)
+
+// BroadcastConsumer reads every partition of its topics directly, without a consumer group, so every
+// running instance receives every event and no group is left behind on the broker when it stops.
+// It starts from the newest offset, commits nothing and doesn't retry: a missed event only matters
+// to the clients connected to the instance at that moment. Partitions added while it runs are picked
+// up after a restart.
+type BroadcastConsumer struct {
+	client     sarama.Client
+	topics     config.KafkaTopics
+	events     map[string]EventHandlerFunc
+	subscribed map[string]struct{}
+	log        *slog.Logger
+}
+
+func NewBroadcastConsumer(cfg *config.Config, name string, log *slog.Logger) *BroadcastConsumer {
+	saramaCfg, err := newSaramaConfig(cfg.Kafka)
+	if err != nil {
+		panic(err)
+	}
+
+	client, err := sarama.NewClient(cfg.Kafka.Brokers, saramaCfg)
+	if err != nil {
+		panic(err)
+	}
+
+	return &BroadcastConsumer{
+		client:     client,
+		topics:     cfg.Kafka.Topics,
+		events:     make(map[string]EventHandlerFunc),
+		subscribed: make(map[string]struct{}),
+		log:        log.With(slog.String("consumer", name)),
+	}
+}
+
+// handleEvent subscribes the consumer to the topic of the event type.
+func (b *BroadcastConsumer) handleEvent(eventType string, handler EventHandlerFunc) {
+	topic, err := topicName(b.topics, schemas[eventType].topic)
+	if err != nil {
+		panic(err)
+	}
+
+	b.events[eventType] = handler
+	b.subscribed[topic] = struct{}{}
+}
+
+// Run consumes the registered topics until ctx is canceled and then closes the client. The partitions
+// are looked up again after retryInterval while a topic is unavailable.
+func (b *BroadcastConsumer) Run(ctx context.Context) error {
+	const op = "kafka.BroadcastConsumer.Run"
+
+	defer b.Close()
+
+	consumer, err := sarama.NewConsumerFromClient(b.client)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	defer consumer.Close()
+
+	var claims []sarama.PartitionConsumer
+
+	for {
+		claims, err = b.consumePartitions(consumer)
+		if err == nil {
+			break
+		}
+
+		b.log.Error("Unable to consume partitions", slog.String("error", err.Error()))
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(retryInterval):
+		}
+	}
+
+	b.log.Info("Consumer started", slog.Int("partitions", len(claims)))
+
+	var wg sync.WaitGroup
+
+	for _, claim := range claims {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			b.consume(ctx, claim)
+		}()
+	}
+
+	<-ctx.Done()
+
+	for _, claim := range claims {
+		claim.AsyncClose()
+	}
+
+	wg.Wait()
+
+	b.log.Info("Consumer stopped")
+
+	return nil
+}
+
+func (b *BroadcastConsumer) Close() {
+	if err := b.client.Close(); err != nil && !errors.Is(err, sarama.ErrClosedClient) {
+		b.log.Error("Unable to close client", slog.String("error", err.Error()))
+	}
+}
+
+// consumePartitions starts a partition consumer at the newest offset for every partition of the topics.
+// The ones already started are closed if any of them fails.
+func (b *BroadcastConsumer) consumePartitions(consumer sarama.Consumer) ([]sarama.PartitionConsumer, error) {
+	const op = "kafka.BroadcastConsumer.consumePartitions"
+
+	var claims []sarama.PartitionConsumer
+
+	for topic := range b.subscribed {
+		partitions, err := b.client.Partitions(topic)
+		if err == nil {
+			for _, partition := range partitions {
+				var claim sarama.PartitionConsumer
+
+				claim, err = consumer.ConsumePartition(topic, partition, sarama.OffsetNewest)
+				if err != nil {
+					break
+				}
+
+				claims = append(claims, claim)
+			}
+		}
+
+		if err != nil {
+			for _, claim := range claims {
+				claim.AsyncClose()
+			}
+
+			return nil, fmt.Errorf("%s: %s: %w", op, topic, err)
+		}
+	}
+
+	return claims, nil
+}
+
+// consume handles the messages of the partition until it is closed. A failed message is logged and skipped.
+func (b *BroadcastConsumer) consume(ctx context.Context, claim sarama.PartitionConsumer) {
+	for msg := range claim.Messages() {
+		if err := dispatchEvent(ctx, b.events, msg); err != nil {
+			b.log.Error(
+				"Unable to handle message, skipped",
+				slog.String("topic", msg.Topic),
+				slog.Int("partition", int(msg.Partition)),
+				slog.Int64("offset", msg.Offset),
+				slog.String("error", err.Error()),
+			)
+
+			continue
+		}
+
+		b.log.Debug("Message handled", slog.String("topic", msg.Topic), slog.Int64("offset", msg.Offset))
+	}
+}
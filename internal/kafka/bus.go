@@ -0,0 +1,175 @@
+package kafka
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+)
+
+const (
+	busQueueSize    = 1024
+	busAttempts     = 3
+	busRetryBackoff = 100 * time.Millisecond
+)
+
+var ErrBusClosed = errors.New("event bus closed")
+
+// Bus is an in-process replacement of Kafka for tests and single-node mode. Every group receives each
+// event it has handlers for and handles events one by one in publish order, like a consumer group with
+// a single partition. Events are kept in memory only: an event that still fails after the retries is
+// logged and dropped.
+type Bus struct {
+	groups []*BusGroup
+	done   chan struct{}
+	once   sync.Once
+	log    *slog.Logger
+}
+
+type BusGroup struct {
+	name     string
+	handlers map[string]EventHandlerFunc
+	queue    chan *Event
+	log      *slog.Logger
+}
+
+func NewBus(log *slog.Logger) *Bus {
+	return &Bus{
+		done: make(chan struct{}),
+		log:  log,
+	}
+}
+
+// Group creates a subscriber group. It must be called before Run.
+func (b *Bus) Group(name string) *BusGroup {
+	g := &BusGroup{
+		name:     name,
+		handlers: make(map[string]EventHandlerFunc),
+		queue:    make(chan *Event, busQueueSize),
+		log:      b.log.With(slog.String("group", name)),
+	}
+
+	b.groups = append(b.groups, g)
+
+	return g
+}
+
+func (g *BusGroup) handleEvent(eventType string, handler EventHandlerFunc) {
+	g.handlers[eventType] = handler
+}
+
+// ProduceEvent delivers the encoded event to the groups that handle its type. It blocks while the queue
+// of a group is full.
+func (b *Bus) ProduceEvent(topic string, value []byte) error {
+	const op = "kafka.Bus.ProduceEvent"
+
+	var event Event
+	if err := json.Unmarshal(value, &event); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := event.CheckCompatibility(); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	select {
+	case <-b.done:
+		return fmt.Errorf("%s: %w", op, ErrBusClosed)
+	default:
+	}
+
+	for _, g := range b.groups {
+		if _, ok := g.handlers[event.Type]; !ok {
+			continue
+		}
+
+		select {
+		case g.queue <- &event:
+		case <-b.done:
+			return fmt.Errorf("%s: %w", op, ErrBusClosed)
+		}
+	}
+
+	b.log.Debug("Event delivered", slog.String("topic", topic), slog.String("type", event.Type), slog.String("id", event.ID))
+
+	return nil
+}
+
+// Run handles the events of every group until the bus is closed and the queues are drained, or until ctx
+// is canceled. Events still queued when ctx is canceled are dropped.
+func (b *Bus) Run(ctx context.Context) {
+	var wg sync.WaitGroup
+
+	for _, g := range b.groups {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			g.run(ctx, b.done)
+		}()
+	}
+
+	go func() {
+		select {
+		case <-ctx.Done():
+			b.Close()
+		case <-b.done:
+		}
+	}()
+
+	wg.Wait()
+}
+
+// Close stops accepting events. Run returns once the groups have handled the events already queued.
+func (b *Bus) Close() {
+	b.once.Do(func() { close(b.done) })
+}
+
+func (g *BusGroup) run(ctx context.Context, done <-chan struct{}) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case event := <-g.queue:
+			g.handle(ctx, event)
+		case <-done:
+			if len(g.queue) == 0 {
+				return
+			}
+		}
+	}
+}
+
+func (g *BusGroup) handle(ctx context.Context, event *Event) {
+	log := g.log.With(slog.String("type", event.Type), slog.String("id", event.ID))
+	backoff := busRetryBackoff
+
+	for attempt := 1; ; attempt++ {
+		err := g.handlers[event.Type](ctx, event)
+		if err == nil {
+			log.Debug("Event handled")
+
+			return
+		}
+
+		if attempt == busAttempts || ctx.Err() != nil {
+			log.Error("Unable to handle event, dropped", slog.Int("attempts", attempt), slog.String("error", err.Error()))
+
+			return
+		}
+
+		log.Warn("Unable to handle event, retrying", slog.Int("attempt", attempt), slog.String("error", err.Error()))
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(backoff):
+		}
+
+		backoff *= 2
+	}
+}
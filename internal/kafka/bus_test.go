@@ -0,0 +1,159 @@
+package kafka
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+type busPayload struct {
+	ID int `json:"id"`
+}
+
+func newTestBus() *Bus {
+	return NewBus(slog.New(slog.NewTextHandler(io.Discard, nil)))
+}
+
+// publish encodes the event and hands it to the bus through the Publisher interface, like the relay does.
+func publish(t *testing.T, p Publisher, eventType string, payload busPayload) {
+	t.Helper()
+
+	event, err := NewEvent(eventType, 1, "1", payload)
+	if err != nil {
+		t.Fatalf("NewEvent: %v", err)
+	}
+
+	value, err := json.Marshal(event)
+	if err != nil {
+		t.Fatalf("marshal: %v", err)
+	}
+
+	if err := p.ProduceEvent(TopicPost, value); err != nil {
+		t.Fatalf("ProduceEvent: %v", err)
+	}
+}
+
+// runBus runs the bus until the test ends, Close drains the queued events before Run returns.
+func runBus(t *testing.T, bus *Bus) <-chan struct{} {
+	t.Helper()
+
+	stopped := make(chan struct{})
+
+	go func() {
+		defer close(stopped)
+
+		bus.Run(context.Background())
+	}()
+
+	t.Cleanup(func() {
+		bus.Close()
+		<-stopped
+	})
+
+	return stopped
+}
+
+func TestBusDelivery(t *testing.T) {
+	bus := newTestBus()
+
+	received := make(chan busPayload, 2)
+	handler := func(ctx context.Context, event *Event, payload busPayload) error {
+		received <- payload
+
+		return nil
+	}
+
+	HandleEvent(bus.Group("feed"), PostCreated, handler)
+	HandleEvent(bus.Group("search"), PostCreated, handler)
+	// A group without a handler for the type doesn't receive the event.
+	HandleEvent(bus.Group("other"), PostDeleted, func(ctx context.Context, event *Event, payload busPayload) error {
+		t.Errorf("unexpected %s event", event.Type)
+
+		return nil
+	})
+
+	runBus(t, bus)
+
+	publish(t, bus, PostCreated, busPayload{ID: 42})
+
+	for range 2 {
+		select {
+		case p := <-received:
+			if p.ID != 42 {
+				t.Errorf("payload = %+v, want ID 42", p)
+			}
+		case <-time.After(time.Second):
+			t.Fatal("event wasn't delivered")
+		}
+	}
+}
+
+func TestBusRetryAndDrop(t *testing.T) {
+	bus := newTestBus()
+
+	group := bus.Group("feed")
+
+	var attempts atomic.Int32
+	HandleEvent(group, PostCreated, func(ctx context.Context, event *Event, payload busPayload) error {
+		attempts.Add(1)
+
+		return errors.New("handler failed")
+	})
+
+	handled := make(chan int, 1)
+	HandleEvent(group, PostDeleted, func(ctx context.Context, event *Event, payload busPayload) error {
+		handled <- payload.ID
+
+		return nil
+	})
+
+	stopped := runBus(t, bus)
+
+	publish(t, bus, PostCreated, busPayload{ID: 1})
+	publish(t, bus, PostDeleted, busPayload{ID: 2})
+
+	bus.Close()
+
+	select {
+	case <-stopped:
+	case <-time.After(5 * time.Second):
+		t.Fatal("bus didn't stop")
+	}
+
+	if got := attempts.Load(); got != busAttempts {
+		t.Errorf("attempts = %d, want %d", got, busAttempts)
+	}
+
+	select {
+	case id := <-handled:
+		if id != 2 {
+			t.Errorf("handled event %d, want 2", id)
+		}
+	default:
+		t.Error("event after the dropped one wasn't handled")
+	}
+}
+
+func TestBusClosed(t *testing.T) {
+	bus := newTestBus()
+	bus.Close()
+
+	event, err := NewEvent(PostCreated, 1, "1", busPayload{ID: 1})
+	if err != nil {
+		t.Fatalf("NewEvent: %v", err)
+	}
+
+	value, err := json.Marshal(event)
+	if err != nil {
+		t.Fatalf("marshal: %v", err)
+	}
+
+	if err := bus.ProduceEvent(TopicPost, value); !errors.Is(err, ErrBusClosed) {
+		t.Fatalf("err = %v, want %v", err, ErrBusClosed)
+	}
+}
@@ -0,0 +1,170 @@
+package kafka
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"github.com/IBM/sarama"
+	"github.com/xdg-go/scram"
+	"kirkagram/internal/config"
+	"os"
+)
+
+// Topics events are grouped by. The names used on the broker are configured in config.KafkaTopics.
+const (
+	TopicPost    = "post"
+	TopicLike    = "like"
+	TopicFollow  = "follow"
+	TopicComment = "comment"
+	TopicUser    = "user"
+
+	TopicNotification = "notification"
+)
+
+// topicName returns the name of the topic on the broker.
+func topicName(topics config.KafkaTopics, topic string) (string, error) {
+	switch topic {
+	case TopicPost:
+		return topics.Post, nil
+	case TopicLike:
+		return topics.Like, nil
+	case TopicFollow:
+		return topics.Follow, nil
+	case TopicComment:
+		return topics.Comment, nil
+	case TopicUser:
+		return topics.User, nil
+	case TopicNotification:
+		return topics.Notification, nil
+	}
+
+	return "", fmt.Errorf("%w: topic %s", ErrUnknownType, topic)
+}
+
+// newSaramaConfig builds the client settings shared by producers and consumers.
+func newSaramaConfig(cfg config.Kafka) (*sarama.Config, error) {
+	const op = "kafka.newSaramaConfig"
+
+	saramaCfg := sarama.NewConfig()
+	saramaCfg.ClientID = cfg.ClientID
+
+	switch cfg.Acks {
+	case "none":
+		saramaCfg.Producer.RequiredAcks = sarama.NoResponse
+	case "leader":
+		saramaCfg.Producer.RequiredAcks = sarama.WaitForLocal
+	default:
+		saramaCfg.Producer.RequiredAcks = sarama.WaitForAll
+	}
+
+	switch cfg.Compression {
+	case "gzip":
+		saramaCfg.Producer.Compression = sarama.CompressionGZIP
+	case "snappy":
+		saramaCfg.Producer.Compression = sarama.CompressionSnappy
+	case "lz4":
+		saramaCfg.Producer.Compression = sarama.CompressionLZ4
+	case "zstd":
+		saramaCfg.Producer.Compression = sarama.CompressionZSTD
+	default:
+		saramaCfg.Producer.Compression = sarama.CompressionNone
+	}
+
+	saramaCfg.Producer.Retry.Max = cfg.Retries
+	saramaCfg.Producer.Retry.Backoff = cfg.RetryBackoff
+	saramaCfg.Producer.Return.Successes = true
+
+	if cfg.TLS.Enabled {
+		tlsCfg, err := newTLSConfig(cfg.TLS)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+
+		saramaCfg.Net.TLS.Enable = true
+		saramaCfg.Net.TLS.Config = tlsCfg
+	}
+
+	if cfg.SASL.Enabled {
+		saramaCfg.Net.SASL.Enable = true
+		saramaCfg.Net.SASL.User = cfg.SASL.User
+		saramaCfg.Net.SASL.Password = cfg.SASL.Password
+
+		switch cfg.SASL.Mechanism {
+		case "SCRAM-SHA-256":
+			saramaCfg.Net.SASL.Mechanism = sarama.SASLTypeSCRAMSHA256
+			saramaCfg.Net.SASL.SCRAMClientGeneratorFunc = func() sarama.SCRAMClient {
+				return &scramClient{hashGenerator: scram.SHA256}
+			}
+		case "SCRAM-SHA-512":
+			saramaCfg.Net.SASL.Mechanism = sarama.SASLTypeSCRAMSHA512
+			saramaCfg.Net.SASL.SCRAMClientGeneratorFunc = func() sarama.SCRAMClient {
+				return &scramClient{hashGenerator: scram.SHA512}
+			}
+		default:
+			saramaCfg.Net.SASL.Mechanism = sarama.SASLTypePlaintext
+		}
+	}
+
+	if err := saramaCfg.Validate(); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return saramaCfg, nil
+}
+
+func newTLSConfig(cfg config.KafkaTLS) (*tls.Config, error) {
+	tlsCfg := &tls.Config{
+		MinVersion:         tls.VersionTLS12,
+		InsecureSkipVerify: cfg.InsecureSkipVerify,
+	}
+
+	if cfg.CAFile != "" {
+		ca, err := os.ReadFile(cfg.CAFile)
+		if err != nil {
+			return nil, err
+		}
+
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(ca) {
+			return nil, fmt.Errorf("no certificates found in %s", cfg.CAFile)
+		}
+
+		tlsCfg.RootCAs = pool
+	}
+
+	if cfg.CertFile != "" {
+		cert, err := tls.LoadX509KeyPair(cfg.CertFile, cfg.KeyFile)
+		if err != nil {
+			return nil, err
+		}
+
+		tlsCfg.Certificates = []tls.Certificate{cert}
+	}
+
+	return tlsCfg, nil
+}
+
+// scramClient adapts xdg-go/scram to sarama.SCRAMClient.
+type scramClient struct {
+	*scram.ClientConversation
+	hashGenerator scram.HashGeneratorFcn
+}
+
+func (c *scramClient) Begin(userName, password, authzID string) error {
+	client, err := c.hashGenerator.NewClient(userName, password, authzID)
+	if err != nil {
+		return err
+	}
+
+	c.ClientConversation = client.NewConversation()
+
+	return nil
+}
+
+func (c *scramClient) Step(challenge string) (string, error) {
+	return c.ClientConversation.Step(challenge)
+}
+
+func (c *scramClient) Done() bool {
+	return c.ClientConversation.Done()
+}
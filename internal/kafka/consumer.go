@@ -0,0 +1,258 @@
+package kafka
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/IBM/sarama"
+	"kirkagram/internal/config"
+	"log/slog"
+	"time"
+)
+
+// retryInterval is the pause before the consumer group rejoins after a failed session.
+const retryInterval = 5 * time.Second
+
+// HandlerFunc processes a single message. A message the handler failed is moved to the next retry topic
+// of the group, and to the dead-letter topic once the retries are exhausted.
+type HandlerFunc func(ctx context.Context, msg *sarama.ConsumerMessage) error
+
+type Consumer struct {
+	group       sarama.ConsumerGroup
+	groupID     string
+	producer    sarama.SyncProducer
+	handlers    map[string]HandlerFunc
+	events      map[string]EventHandlerFunc
+	topics      config.KafkaTopics
+	retryDelays []time.Duration
+	stages      map[string]retryStage
+	log         *slog.Logger
+}
+
+func NewConsumer(cfg *config.Config, groupID string, log *slog.Logger) *Consumer {
+	saramaCfg, err := newSaramaConfig(cfg.Kafka)
+	if err != nil {
+		panic(err)
+	}
+	saramaCfg.Consumer.Offsets.Initial = sarama.OffsetOldest
+
+	group, err := sarama.NewConsumerGroup(cfg.Kafka.Brokers, groupID, saramaCfg)
+	if err != nil {
+		panic(err)
+	}
+
+	producer, err := sarama.NewSyncProducer(cfg.Kafka.Brokers, saramaCfg)
+	if err != nil {
+		panic(err)
+	}
+
+	return &Consumer{
+		group:       group,
+		groupID:     groupID,
+		producer:    producer,
+		handlers:    make(map[string]HandlerFunc),
+		events:      make(map[string]EventHandlerFunc),
+		topics:      cfg.Kafka.Topics,
+		retryDelays: cfg.Kafka.Retry.Delays,
+		stages:      make(map[string]retryStage),
+		log:         log.With(slog.String("group", groupID)),
+	}
+}
+
+// Handle registers the handler for every message of the topic. It must be called before Run.
+func (c *Consumer) Handle(topic string, handler HandlerFunc) {
+	c.handlers[topic] = handler
+}
+
+// handleEvent subscribes the consumer to the topic of the event type.
+func (c *Consumer) handleEvent(eventType string, handler EventHandlerFunc) {
+	topic, err := topicName(c.topics, schemas[eventType].topic)
+	if err != nil {
+		panic(err)
+	}
+
+	c.events[eventType] = handler
+	c.handlers[topic] = c.dispatch
+}
+
+// dispatch decodes the envelope and passes the event to the handler of its type.
+func (c *Consumer) dispatch(ctx context.Context, msg *sarama.ConsumerMessage) error {
+	const op = "kafka.Consumer.dispatch"
+
+	if err := dispatchEvent(ctx, c.events, msg); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+// dispatchEvent decodes the envelope of msg and passes the event to the handler of its type.
+// Events of other types share the topic with the registered ones and are skipped.
+func dispatchEvent(ctx context.Context, events map[string]EventHandlerFunc, msg *sarama.ConsumerMessage) error {
+	var event Event
+	if err := json.Unmarshal(msg.Value, &event); err != nil {
+		return err
+	}
+
+	handler, ok := events[event.Type]
+	if !ok {
+		return nil
+	}
+
+	if err := event.CheckCompatibility(); err != nil {
+		return err
+	}
+
+	return handler(ctx, &event)
+}
+
+// Run consumes the registered topics and their retry topics until ctx is canceled and then closes
+// the consumer group.
+func (c *Consumer) Run(ctx context.Context) error {
+	defer c.Close()
+
+	topics := make([]string, 0, len(c.handlers)*(len(c.retryDelays)+1))
+	for topic := range c.handlers {
+		topics = append(topics, topic)
+
+		for i, delay := range c.retryDelays {
+			retryTopic := RetryTopic(topic, c.groupID, i+1)
+
+			c.stages[retryTopic] = retryStage{source: topic, attempt: i + 1, delay: delay}
+			topics = append(topics, retryTopic)
+		}
+	}
+
+	c.log.Info("Consumer started", slog.Any("topics", topics))
+
+	for {
+		// Consume returns on every rebalance and on handler errors, so it is called until shutdown.
+		err := c.group.Consume(ctx, topics, &groupHandler{consumer: c})
+		if err != nil {
+			if errors.Is(err, sarama.ErrClosedConsumerGroup) {
+				return nil
+			}
+
+			c.log.Error("Consume session failed", slog.String("error", err.Error()))
+
+			select {
+			case <-ctx.Done():
+			case <-time.After(retryInterval):
+			}
+		}
+
+		if ctx.Err() != nil {
+			c.log.Info("Consumer stopped")
+
+			return nil
+		}
+	}
+}
+
+func (c *Consumer) Close() {
+	if err := c.group.Close(); err != nil {
+		c.log.Error("Unable to close consumer group", slog.String("error", err.Error()))
+	}
+
+	if err := c.producer.Close(); err != nil {
+		c.log.Error("Unable to close retry producer", slog.String("error", err.Error()))
+	}
+}
+
+// process handles a message of a source or a retry topic. A failed message is parked in the next retry
+// topic or in the dead-letter topic, an error is returned only if the message could not be parked.
+func (c *Consumer) process(ctx context.Context, msg *sarama.ConsumerMessage, log *slog.Logger) error {
+	const op = "kafka.Consumer.process"
+
+	source := msg.Topic
+	attempt := 0
+
+	if stage, ok := c.stages[msg.Topic]; ok {
+		if err := waitForRetry(ctx, msg, stage.delay); err != nil {
+			return fmt.Errorf("%s: %w", op, err)
+		}
+
+		source = stage.source
+		attempt = stage.attempt
+	}
+
+	handler, ok := c.handlers[source]
+	if !ok {
+		log.Warn("No handler registered for topic")
+
+		return nil
+	}
+
+	err := handler(ctx, msg)
+	if err == nil {
+		log.Debug("Message handled")
+
+		return nil
+	}
+
+	if ctx.Err() != nil {
+		// The handler was interrupted by shutdown, the message is consumed again after restart.
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	target := DeadLetterTopic(source, c.groupID)
+	if attempt < len(c.retryDelays) {
+		target = RetryTopic(source, c.groupID, attempt+1)
+	}
+
+	if _, _, parkErr := c.producer.SendMessage(failedMessage(msg, source, target, attempt+1, err)); parkErr != nil {
+		return fmt.Errorf("%s: %w", op, errors.Join(err, parkErr))
+	}
+
+	log.Error(
+		"Unable to handle message, parked",
+		slog.String("error", err.Error()),
+		slog.Int("attempt", attempt+1),
+		slog.String("parkedTo", target),
+	)
+
+	return nil
+}
+
+type groupHandler struct {
+	consumer *Consumer
+}
+
+func (h *groupHandler) Setup(sarama.ConsumerGroupSession) error {
+	return nil
+}
+
+func (h *groupHandler) Cleanup(sarama.ConsumerGroupSession) error {
+	return nil
+}
+
+func (h *groupHandler) ConsumeClaim(session sarama.ConsumerGroupSession, claim sarama.ConsumerGroupClaim) error {
+	const op = "kafka.groupHandler.ConsumeClaim"
+
+	for {
+		select {
+		case msg, ok := <-claim.Messages():
+			if !ok {
+				return nil
+			}
+
+			log := h.consumer.log.With(
+				slog.String("topic", msg.Topic),
+				slog.Int("partition", int(msg.Partition)),
+				slog.Int64("offset", msg.Offset),
+			)
+
+			if err := h.consumer.process(session.Context(), msg, log); err != nil {
+				log.Error("Unable to process message", slog.String("error", err.Error()))
+
+				return fmt.Errorf("%s: %w", op, err)
+			}
+
+			// Marked offsets are committed by the auto commit loop and when the group is closed.
+			session.MarkMessage(msg, "")
+		case <-session.Context().Done():
+			return nil
+		}
+	}
+}
@@ -0,0 +1,231 @@
+package kafka
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/IBM/sarama"
+	"kirkagram/internal/config"
+	"log/slog"
+)
+
+// ErrNoRetryTopics is returned by ReplayDeadLetters when no retry delays are configured: the group
+// consumes no retry topic the messages could be replayed to. ReplayOptions.ToSource replays without them.
+var ErrNoRetryTopics = errors.New("no retry topics configured")
+
+// replayGroupSuffix names the consumer group that remembers how far a dead-letter topic was replayed,
+// so running the replay twice does not publish the same message twice.
+const replayGroupSuffix = ".replay"
+
+// ReplayOptions selects the dead-letter messages to hand back to the group that failed them.
+type ReplayOptions struct {
+	// GroupID is the consumer group that failed the messages.
+	GroupID string
+	// Topic is the source topic name on the broker.
+	Topic string
+	// Limit stops the replay after this many messages, 0 replays all of them.
+	Limit int
+	// DryRun logs the messages without publishing them or moving the replay offset.
+	DryRun bool
+	// ToSource replays to the source topic instead of the retry topic of the group. Every group
+	// consuming the source topic receives the messages again, not only the one that failed them.
+	ToSource bool
+}
+
+// ReplayDeadLetters publishes the messages parked in the dead-letter topic to the first retry topic of
+// the group, or to the source topic with ToSource. The source topic is shared by every group, so replaying
+// there redelivers the messages to the groups that already handled them as well. The attempt and the error are removed from the headers, the
+// original topic, partition and offset are kept. Only messages that were in the dead-letter topic when
+// the replay started are replayed. It returns the number of replayed messages.
+func ReplayDeadLetters(ctx context.Context, cfg *config.Config, opts ReplayOptions, log *slog.Logger) (int, error) {
+	const op = "kafka.ReplayDeadLetters"
+
+	if !opts.ToSource && len(cfg.Kafka.Retry.Delays) == 0 {
+		return 0, fmt.Errorf("%s: %w", op, ErrNoRetryTopics)
+	}
+
+	saramaCfg, err := newSaramaConfig(cfg.Kafka)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+	saramaCfg.Consumer.Offsets.Initial = sarama.OffsetOldest
+
+	client, err := sarama.NewClient(cfg.Kafka.Brokers, saramaCfg)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+	defer client.Close()
+
+	producer, err := sarama.NewSyncProducerFromClient(client)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+	defer producer.Close()
+
+	consumer, err := sarama.NewConsumerFromClient(client)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+	defer consumer.Close()
+
+	dlq := DeadLetterTopic(opts.Topic, opts.GroupID)
+
+	offsets, err := sarama.NewOffsetManagerFromClient(dlq+replayGroupSuffix, client)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+	defer offsets.Close()
+
+	partitions, err := client.Partitions(dlq)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	r := &replayer{
+		client:   client,
+		consumer: consumer,
+		offsets:  offsets,
+		producer: producer,
+		dlq:      dlq,
+		opts:     opts,
+		log:      log.With(slog.String("topic", dlq)),
+	}
+
+	replayed := 0
+
+	for _, partition := range partitions {
+		limit := 0
+		if opts.Limit > 0 {
+			if replayed >= opts.Limit {
+				break
+			}
+
+			limit = opts.Limit - replayed
+		}
+
+		num, err := r.replayPartition(ctx, partition, limit)
+		replayed += num
+		if err != nil {
+			return replayed, fmt.Errorf("%s: %w", op, err)
+		}
+	}
+
+	return replayed, nil
+}
+
+type replayer struct {
+	client   sarama.Client
+	consumer sarama.Consumer
+	offsets  sarama.OffsetManager
+	producer sarama.SyncProducer
+	dlq      string
+	opts     ReplayOptions
+	log      *slog.Logger
+}
+
+// replayPartition replays up to limit messages of the partition, 0 means no limit.
+func (r *replayer) replayPartition(ctx context.Context, partition int32, limit int) (int, error) {
+	end, err := r.client.GetOffset(r.dlq, partition, sarama.OffsetNewest)
+	if err != nil {
+		return 0, err
+	}
+
+	pom, err := r.offsets.ManagePartition(r.dlq, partition)
+	if err != nil {
+		return 0, err
+	}
+	defer pom.Close()
+
+	start, _ := pom.NextOffset()
+	if start == sarama.OffsetOldest {
+		if start, err = r.client.GetOffset(r.dlq, partition, sarama.OffsetOldest); err != nil {
+			return 0, err
+		}
+	}
+
+	if start >= end {
+		return 0, nil
+	}
+
+	pc, err := r.consumer.ConsumePartition(r.dlq, partition, start)
+	if err != nil {
+		return 0, err
+	}
+	defer pc.Close()
+
+	replayed := 0
+
+	for limit == 0 || replayed < limit {
+		var msg *sarama.ConsumerMessage
+
+		select {
+		case <-ctx.Done():
+			return replayed, ctx.Err()
+		case msg = <-pc.Messages():
+		}
+
+		if msg == nil {
+			return replayed, errors.New("partition consumer closed")
+		}
+
+		source := headerValue(msg.Headers, HeaderOriginalTopic)
+		if source == "" {
+			source = r.opts.Topic
+		}
+
+		target := source
+		if !r.opts.ToSource {
+			target = RetryTopic(source, r.opts.GroupID, 1)
+		}
+
+		r.log.Info(
+			"Replaying message",
+			slog.Int("partition", int(msg.Partition)),
+			slog.Int64("offset", msg.Offset),
+			slog.String("to", target),
+			slog.String("attempt", headerValue(msg.Headers, HeaderAttempt)),
+			slog.String("error", headerValue(msg.Headers, HeaderError)),
+		)
+
+		if !r.opts.DryRun {
+			if _, _, err := r.producer.SendMessage(replayedMessage(msg, target)); err != nil {
+				return replayed, err
+			}
+
+			pom.MarkOffset(msg.Offset+1, "")
+		}
+
+		replayed++
+
+		if msg.Offset+1 >= end {
+			break
+		}
+	}
+
+	return replayed, nil
+}
+
+// replayedMessage copies the dead-letter message to the topic without the attempt and the error.
+// The original headers stay, so a message failing again still points to where it was first consumed.
+func replayedMessage(msg *sarama.ConsumerMessage, topic string) *sarama.ProducerMessage {
+	headers := make([]sarama.RecordHeader, 0, len(msg.Headers))
+	for _, header := range msg.Headers {
+		switch string(header.Key) {
+		case HeaderAttempt, HeaderError, HeaderFailedAt:
+		default:
+			headers = append(headers, *header)
+		}
+	}
+
+	replayed := &sarama.ProducerMessage{
+		Topic:   topic,
+		Value:   sarama.ByteEncoder(msg.Value),
+		Headers: headers,
+	}
+
+	if msg.Key != nil {
+		replayed.Key = sarama.ByteEncoder(msg.Key)
+	}
+
+	return replayed
+}
@@ -0,0 +1,146 @@
+package kafka
+
+import (
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// Event types published by the application. A type names the change, the topic groups the types of one entity.
+const (
+	PostCreated    = "post.created"
+	PostDeleted    = "post.deleted"
+	LikeCreated    = "like.created"
+	LikeDeleted    = "like.deleted"
+	FollowCreated  = "follow.created"
+	FollowDeleted  = "follow.deleted"
+	CommentCreated = "comment.created"
+	UserUpdated    = "user.updated"
+	UserDeleted    = "user.deleted"
+
+	NotificationUpdated = "notification.updated"
+)
+
+// Message headers duplicating the envelope fields, so consumers can route events without decoding the value.
+const (
+	HeaderEventID      = "event-id"
+	HeaderEventType    = "event-type"
+	HeaderEventVersion = "event-version"
+	HeaderOccurredAt   = "occurred-at"
+	HeaderActorID      = "actor-id"
+)
+
+var ErrUnsupportedVersion = errors.New("unsupported event version")
+
+type schema struct {
+	topic string
+	// version changes only when the payload changes incompatibly. New optional fields keep the version,
+	// consumers ignore fields they do not know.
+	version int
+}
+
+var schemas = map[string]schema{
+	PostCreated:    {topic: TopicPost, version: 1},
+	PostDeleted:    {topic: TopicPost, version: 1},
+	LikeCreated:    {topic: TopicLike, version: 1},
+	LikeDeleted:    {topic: TopicLike, version: 1},
+	FollowCreated:  {topic: TopicFollow, version: 1},
+	FollowDeleted:  {topic: TopicFollow, version: 1},
+	CommentCreated: {topic: TopicComment, version: 1},
+	UserUpdated:    {topic: TopicUser, version: 1},
+	UserDeleted:    {topic: TopicUser, version: 1},
+
+	NotificationUpdated: {topic: TopicNotification, version: 1},
+}
+
+// Event is the envelope of every message on the application topics.
+type Event struct {
+	ID         string    `json:"id"`
+	Type       string    `json:"type"`
+	Version    int       `json:"version"`
+	OccurredAt time.Time `json:"occurred_at"`
+	ActorID    int       `json:"actor_id"`
+	// Key is the partition key. Events with the same key are consumed in the order they were published.
+	Key     string          `json:"key"`
+	Payload json.RawMessage `json:"payload"`
+}
+
+// NewEvent wraps the payload into an envelope of the current version of the event type.
+func NewEvent(eventType string, actorID int, key string, payload any) (*Event, error) {
+	const op = "kafka.NewEvent"
+
+	s, ok := schemas[eventType]
+	if !ok {
+		return nil, fmt.Errorf("%s: %w: %s", op, ErrUnknownType, eventType)
+	}
+
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	ID, err := newEventID()
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return &Event{
+		ID:         ID,
+		Type:       eventType,
+		Version:    s.version,
+		OccurredAt: time.Now().UTC(),
+		ActorID:    actorID,
+		Key:        key,
+		Payload:    data,
+	}, nil
+}
+
+// Topic returns the topic events of the type are grouped by. The producer maps it to the configured topic name.
+func (e *Event) Topic() (string, error) {
+	const op = "kafka.Event.Topic"
+
+	s, ok := schemas[e.Type]
+	if !ok {
+		return "", fmt.Errorf("%s: %w: %s", op, ErrUnknownType, e.Type)
+	}
+
+	return s.topic, nil
+}
+
+// CheckCompatibility reports whether this build can read the event. Unknown types are rejected as well,
+// a consumer only receives the types it registered handlers for.
+func (e *Event) CheckCompatibility() error {
+	const op = "kafka.Event.CheckCompatibility"
+
+	s, ok := schemas[e.Type]
+	if !ok {
+		return fmt.Errorf("%s: %w: %s", op, ErrUnknownType, e.Type)
+	}
+
+	if !s.supports(e.Version) {
+		return fmt.Errorf("%s: %w: %s v%d", op, ErrUnsupportedVersion, e.Type, e.Version)
+	}
+
+	return nil
+}
+
+// supports reports whether a consumer of the current version reads events of the version.
+// Older versions are read as well, their payloads are a subset of the current one.
+func (s schema) supports(version int) bool {
+	return version >= 1 && version <= s.version
+}
+
+// newEventID returns a random UUID v4.
+func newEventID() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+
+	b[6] = b[6]&0x0f | 0x40
+	b[8] = b[8]&0x3f | 0x80
+
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:16]), nil
+}
@@ -0,0 +1,107 @@
+package kafka
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+)
+
+func TestEventRoundTrip(t *testing.T) {
+	type payload struct {
+		ID   int    `json:"id"`
+		Name string `json:"name"`
+	}
+
+	for eventType, s := range schemas {
+		t.Run(eventType, func(t *testing.T) {
+			event, err := NewEvent(eventType, 7, "42", payload{ID: 42, Name: "kirk"})
+			if err != nil {
+				t.Fatalf("NewEvent: %v", err)
+			}
+
+			if event.Version != s.version {
+				t.Errorf("version = %d, want %d", event.Version, s.version)
+			}
+
+			data, err := json.Marshal(event)
+			if err != nil {
+				t.Fatalf("marshal: %v", err)
+			}
+
+			var decoded Event
+			if err := json.Unmarshal(data, &decoded); err != nil {
+				t.Fatalf("unmarshal: %v", err)
+			}
+
+			if err := decoded.CheckCompatibility(); err != nil {
+				t.Fatalf("CheckCompatibility: %v", err)
+			}
+
+			if decoded.ID != event.ID || decoded.Type != eventType || decoded.ActorID != 7 || decoded.Key != "42" {
+				t.Errorf("decoded = %+v, want %+v", decoded, *event)
+			}
+
+			if !decoded.OccurredAt.Equal(event.OccurredAt) {
+				t.Errorf("occurred_at = %v, want %v", decoded.OccurredAt, event.OccurredAt)
+			}
+
+			topic, err := decoded.Topic()
+			if err != nil || topic != s.topic {
+				t.Errorf("Topic() = %q, %v, want %q", topic, err, s.topic)
+			}
+
+			var p payload
+			if err := json.Unmarshal(decoded.Payload, &p); err != nil {
+				t.Fatalf("unmarshal payload: %v", err)
+			}
+
+			if p != (payload{ID: 42, Name: "kirk"}) {
+				t.Errorf("payload = %+v", p)
+			}
+		})
+	}
+}
+
+func TestNewEventUnknownType(t *testing.T) {
+	_, err := NewEvent("post.archived", 1, "1", struct{}{})
+	if !errors.Is(err, ErrUnknownType) {
+		t.Fatalf("err = %v, want %v", err, ErrUnknownType)
+	}
+}
+
+func TestCheckCompatibility(t *testing.T) {
+	tests := []struct {
+		name    string
+		event   Event
+		wantErr error
+	}{
+		{name: "current version", event: Event{Type: PostCreated, Version: 1}},
+		{name: "newer version", event: Event{Type: PostCreated, Version: 2}, wantErr: ErrUnsupportedVersion},
+		{name: "zero version", event: Event{Type: PostCreated, Version: 0}, wantErr: ErrUnsupportedVersion},
+		{name: "unknown type", event: Event{Type: "post.archived", Version: 1}, wantErr: ErrUnknownType},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.event.CheckCompatibility()
+
+			if tt.wantErr == nil && err != nil {
+				t.Fatalf("err = %v, want nil", err)
+			}
+
+			if tt.wantErr != nil && !errors.Is(err, tt.wantErr) {
+				t.Fatalf("err = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestSchemaSupports(t *testing.T) {
+	s := schema{topic: TopicPost, version: 3}
+
+	for version, want := range map[int]bool{0: false, 1: true, 2: true, 3: true, 4: false} {
+		if got := s.supports(version); got != want {
+			t.Errorf("supports(%d) = %v, want %v", version, got, want)
+		}
+	}
+}
@@ -0,0 +1,102 @@
+package kafka
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"kirkagram/internal/models"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// payloads maps every event type to the struct its consumers decode the payload into.
+var payloads = map[string]func() any{
+	PostCreated:         func() any { return new(models.Posts) },
+	PostDeleted:         func() any { return new(models.Posts) },
+	LikeCreated:         func() any { return new(models.LikeRequest) },
+	LikeDeleted:         func() any { return new(models.LikeRequest) },
+	FollowCreated:       func() any { return new(models.FollowRequest) },
+	FollowDeleted:       func() any { return new(models.FollowRequest) },
+	CommentCreated:      func() any { return new(models.Comments) },
+	UserUpdated:         func() any { return new(models.UserEvent) },
+	UserDeleted:         func() any { return new(models.UserDeletedEvent) },
+	NotificationUpdated: func() any { return new(models.NotificationUpdatedEvent) },
+}
+
+// TestEventFixtures decodes an event of every version still supported, as it was published, into the
+// current payload struct. testdata/events keeps one <type>.v<version>.json per version. A fixture is never
+// edited: renaming, removing or retyping a payload field fails here and needs a new version.
+func TestEventFixtures(t *testing.T) {
+	for eventType, s := range schemas {
+		newPayload, ok := payloads[eventType]
+		if !ok {
+			t.Errorf("%s: no payload struct registered in payloads", eventType)
+
+			continue
+		}
+
+		for version := 1; version <= s.version; version++ {
+			name := fmt.Sprintf("%s.v%d", eventType, version)
+
+			t.Run(name, func(t *testing.T) {
+				data, err := os.ReadFile(filepath.Join("testdata", "events", name+".json"))
+				if err != nil {
+					t.Fatalf("golden fixture is missing: %v", err)
+				}
+
+				var event Event
+				if err := json.Unmarshal(data, &event); err != nil {
+					t.Fatalf("unmarshal envelope: %v", err)
+				}
+
+				if event.Type != eventType || event.Version != version {
+					t.Fatalf("fixture is %s v%d", event.Type, event.Version)
+				}
+
+				if err := event.CheckCompatibility(); err != nil {
+					t.Fatalf("CheckCompatibility: %v", err)
+				}
+
+				payload := newPayload()
+
+				decoder := json.NewDecoder(bytes.NewReader(event.Payload))
+				decoder.DisallowUnknownFields()
+
+				if err := decoder.Decode(payload); err != nil {
+					t.Fatalf("decode payload into %T: %v", payload, err)
+				}
+
+				diffFields(t, event.Payload, payload)
+			})
+		}
+	}
+}
+
+// diffFields encodes the decoded payload again and reports the fields of the fixture whose values changed,
+// for example a number decoded into a string field with the ",string" option.
+func diffFields(t *testing.T, fixture json.RawMessage, payload any) {
+	t.Helper()
+
+	encoded, err := json.Marshal(payload)
+	if err != nil {
+		t.Fatalf("marshal payload: %v", err)
+	}
+
+	var want, got map[string]any
+
+	if err := json.Unmarshal(fixture, &want); err != nil {
+		t.Fatalf("unmarshal fixture payload: %v", err)
+	}
+
+	if err := json.Unmarshal(encoded, &got); err != nil {
+		t.Fatalf("unmarshal payload: %v", err)
+	}
+
+	for field, value := range want {
+		if !reflect.DeepEqual(got[field], value) {
+			t.Errorf("field %s = %v, fixture has %v", field, got[field], value)
+		}
+	}
+}
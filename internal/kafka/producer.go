@@ -1,39 +1,86 @@
 package kafka
 
 import (
+	"encoding/json"
 	"errors"
 	"fmt"
 	"github.com/IBM/sarama"
 	"kirkagram/internal/config"
 	"log/slog"
 	"strconv"
+	"time"
 )
 
 type Producer struct {
 	producer sarama.SyncProducer
+	topics   config.KafkaTopics
 	log      *slog.Logger
 }
 
 var ErrUnknownType = errors.New("unknown type")
 
+// IsPermanent reports whether publishing failed because of the message itself, so retrying it can't help.
+func IsPermanent(err error) bool {
+	var syntaxErr *json.SyntaxError
+	var typeErr *json.UnmarshalTypeError
+
+	switch {
+	case errors.Is(err, ErrUnknownType), errors.Is(err, ErrUnsupportedVersion):
+		return true
+	case errors.As(err, &syntaxErr), errors.As(err, &typeErr):
+		return true
+	case errors.Is(err, sarama.ErrMessageSizeTooLarge), errors.Is(err, sarama.ErrInvalidMessage),
+		errors.Is(err, sarama.ErrInvalidMessageSize), errors.Is(err, sarama.ErrInvalidRecord):
+		return true
+	}
+
+	return false
+}
+
 func NewProducer(cfg *config.Config, log *slog.Logger) *Producer {
-	p, err := sarama.NewSyncProducer([]string{"localhost:29092"}, nil)
+	saramaCfg, err := newSaramaConfig(cfg.Kafka)
+	if err != nil {
+		panic(err)
+	}
+
+	p, err := sarama.NewSyncProducer(cfg.Kafka.Brokers, saramaCfg)
 	if err != nil {
 		panic(err)
 	}
 
 	return &Producer{
 		producer: p,
+		topics:   cfg.Kafka.Topics,
 		log:      log,
 	}
 }
 
-func (p *Producer) Produce(msg []byte, topic string) error {
-	const op = "kafka.Produce"
+// ProduceEvent publishes an encoded Event to the configured name of the topic. The event key becomes
+// the message key and the envelope fields are copied to the message headers.
+func (p *Producer) ProduceEvent(topic string, value []byte) error {
+	const op = "kafka.ProduceEvent"
+
+	topic, err := topicName(p.topics, topic)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	var event Event
+	if err := json.Unmarshal(value, &event); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
 
 	produceMsg := &sarama.ProducerMessage{
 		Topic: topic,
-		Value: sarama.ByteEncoder(msg),
+		Key:   sarama.StringEncoder(event.Key),
+		Value: sarama.ByteEncoder(value),
+		Headers: []sarama.RecordHeader{
+			{Key: []byte(HeaderEventID), Value: []byte(event.ID)},
+			{Key: []byte(HeaderEventType), Value: []byte(event.Type)},
+			{Key: []byte(HeaderEventVersion), Value: []byte(strconv.Itoa(event.Version))},
+			{Key: []byte(HeaderOccurredAt), Value: []byte(event.OccurredAt.Format(time.RFC3339Nano))},
+			{Key: []byte(HeaderActorID), Value: []byte(strconv.Itoa(event.ActorID))},
+		},
 	}
 
 	partition, offset, err := p.producer.SendMessage(produceMsg)
@@ -42,13 +89,12 @@ func (p *Producer) Produce(msg []byte, topic string) error {
 	}
 
 	p.log.Info(
-		"Message sent",
+		"Event sent",
 		slog.String("topic", topic),
-		slog.String("partition", strconv.FormatInt(int64(partition), 10)),
-		slog.String(
-			"offset",
-			strconv.FormatInt(int64(offset), 10),
-		),
+		slog.String("type", event.Type),
+		slog.String("id", event.ID),
+		slog.Int("partition", int(partition)),
+		slog.Int64("offset", offset),
 	)
 
 	return nil
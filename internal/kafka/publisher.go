@@ -0,0 +1,51 @@
+package kafka
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+)
+
+// Publisher publishes encoded events. Producer sends them to Kafka, Bus delivers them in process.
+type Publisher interface {
+	ProduceEvent(topic string, value []byte) error
+}
+
+var (
+	_ Publisher = (*Producer)(nil)
+	_ Publisher = (*Bus)(nil)
+)
+
+// EventHandlerFunc processes a single event of the type it was registered for.
+type EventHandlerFunc func(ctx context.Context, event *Event) error
+
+// Registry receives event handlers. It is implemented by Consumer, BroadcastConsumer and by the groups
+// of Bus, so the same registration code serves Kafka and the in-memory mode.
+type Registry interface {
+	handleEvent(eventType string, handler EventHandlerFunc)
+}
+
+var (
+	_ Registry = (*Consumer)(nil)
+	_ Registry = (*BroadcastConsumer)(nil)
+	_ Registry = (*BusGroup)(nil)
+)
+
+// HandleEvent registers the handler for events of the type. The payload is decoded into T.
+// It must be called before the consumer or the bus is started.
+func HandleEvent[T any](r Registry, eventType string, handler func(ctx context.Context, event *Event, payload T) error) {
+	if _, ok := schemas[eventType]; !ok {
+		panic(fmt.Sprintf("kafka: %s: %s", ErrUnknownType, eventType))
+	}
+
+	r.handleEvent(eventType, func(ctx context.Context, event *Event) error {
+		const op = "kafka.HandleEvent"
+
+		var payload T
+		if err := json.Unmarshal(event.Payload, &payload); err != nil {
+			return fmt.Errorf("%s: %w", op, err)
+		}
+
+		return handler(ctx, event, payload)
+	})
+}
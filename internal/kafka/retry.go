@@ -0,0 +1,132 @@
+package kafka
+
+import (
+	"context"
+	"fmt"
+	"github.com/IBM/sarama"
+	"strconv"
+	"time"
+)
+
+// Headers describing why a message was moved to a retry or dead-letter topic.
+const (
+	HeaderOriginalTopic     = "x-original-topic"
+	HeaderOriginalPartition = "x-original-partition"
+	HeaderOriginalOffset    = "x-original-offset"
+	HeaderAttempt           = "x-attempt"
+	HeaderError             = "x-error"
+	HeaderFailedAt          = "x-failed-at"
+)
+
+var failureHeaders = []string{
+	HeaderOriginalTopic,
+	HeaderOriginalPartition,
+	HeaderOriginalOffset,
+	HeaderAttempt,
+	HeaderError,
+	HeaderFailedAt,
+}
+
+// RetryTopic returns the name of the n-th retry topic of the consumer group for the topic, n starts at 1.
+// Retry topics belong to a group, so a message failed by one group is not redelivered to the others.
+func RetryTopic(topic string, groupID string, n int) string {
+	return fmt.Sprintf("%s.%s.retry.%d", topic, groupID, n)
+}
+
+// DeadLetterTopic returns the name of the topic that keeps the messages the group failed to handle
+// after all retries.
+func DeadLetterTopic(topic string, groupID string) string {
+	return fmt.Sprintf("%s.%s.dlq", topic, groupID)
+}
+
+// retryStage describes a retry topic: the topic its messages came from, how many times they failed
+// and how long they wait before the next attempt.
+type retryStage struct {
+	source  string
+	attempt int
+	delay   time.Duration
+}
+
+// waitForRetry blocks until the message has spent delay in the retry topic.
+func waitForRetry(ctx context.Context, msg *sarama.ConsumerMessage, delay time.Duration) error {
+	wait := time.Until(msg.Timestamp.Add(delay))
+	if wait <= 0 {
+		return nil
+	}
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-time.After(wait):
+		return nil
+	}
+}
+
+// failedMessage copies msg to topic with the failure recorded in headers. The original topic,
+// partition and offset are taken from the first failure.
+func failedMessage(msg *sarama.ConsumerMessage, source string, topic string, attempt int, cause error) *sarama.ProducerMessage {
+	headers := make([]sarama.RecordHeader, 0, len(msg.Headers)+len(failureHeaders))
+	original := map[string]string{
+		HeaderOriginalTopic:     source,
+		HeaderOriginalPartition: strconv.Itoa(int(msg.Partition)),
+		HeaderOriginalOffset:    strconv.FormatInt(msg.Offset, 10),
+	}
+
+	for _, header := range msg.Headers {
+		key := string(header.Key)
+
+		if _, ok := original[key]; ok {
+			original[key] = string(header.Value)
+
+			continue
+		}
+
+		if isFailureHeader(key) {
+			continue
+		}
+
+		headers = append(headers, *header)
+	}
+
+	for _, key := range []string{HeaderOriginalTopic, HeaderOriginalPartition, HeaderOriginalOffset} {
+		headers = append(headers, sarama.RecordHeader{Key: []byte(key), Value: []byte(original[key])})
+	}
+
+	headers = append(headers,
+		sarama.RecordHeader{Key: []byte(HeaderAttempt), Value: []byte(strconv.Itoa(attempt))},
+		sarama.RecordHeader{Key: []byte(HeaderError), Value: []byte(cause.Error())},
+		sarama.RecordHeader{Key: []byte(HeaderFailedAt), Value: []byte(time.Now().UTC().Format(time.RFC3339Nano))},
+	)
+
+	failed := &sarama.ProducerMessage{
+		Topic:   topic,
+		Value:   sarama.ByteEncoder(msg.Value),
+		Headers: headers,
+	}
+
+	if msg.Key != nil {
+		failed.Key = sarama.ByteEncoder(msg.Key)
+	}
+
+	return failed
+}
+
+func isFailureHeader(key string) bool {
+	for _, header := range failureHeaders {
+		if key == header {
+			return true
+		}
+	}
+
+	return false
+}
+
+func headerValue(headers []*sarama.RecordHeader, key string) string {
+	for _, header := range headers {
+		if string(header.Key) == key {
+			return string(header.Value)
+		}
+	}
+
+	return ""
+}
@@ -0,0 +1,57 @@
+package jwt
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+var ErrInvalidToken = errors.New("Invalid token")
+
+func NewToken(userID int, secret string, ttl time.Duration) (string, error) {
+	const op = "lib.jwt.NewToken"
+
+	now := time.Now()
+
+	claims := jwt.RegisteredClaims{
+		Subject:   strconv.Itoa(userID),
+		IssuedAt:  jwt.NewNumericDate(now),
+		ExpiresAt: jwt.NewNumericDate(now.Add(ttl)),
+	}
+
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString([]byte(secret))
+	if err != nil {
+		return "", fmt.Errorf("%s: %w", op, err)
+	}
+
+	return token, nil
+}
+
+// ParseToken validates the signature and expiry of the token and returns the user ID it was issued for.
+func ParseToken(tokenString string, secret string) (int, error) {
+	const op = "lib.jwt.ParseToken"
+
+	var claims jwt.RegisteredClaims
+
+	_, err := jwt.ParseWithClaims(
+		tokenString,
+		&claims,
+		func(token *jwt.Token) (interface{}, error) {
+			return []byte(secret), nil
+		},
+		jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}),
+	)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, ErrInvalidToken)
+	}
+
+	userID, err := strconv.Atoi(claims.Subject)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, ErrInvalidToken)
+	}
+
+	return userID, nil
+}
@@ -0,0 +1,85 @@
+package pagination
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"kirkagram/internal/models"
+)
+
+const (
+	DefaultLimit = 20
+	MaxLimit     = 100
+)
+
+var ErrInvalidCursor = errors.New("Invalid cursor")
+
+// Limit clamps the requested page size to (0, MaxLimit], falling back to DefaultLimit.
+func Limit(limit int) int {
+	if limit <= 0 {
+		return DefaultLimit
+	}
+
+	if limit > MaxLimit {
+		return MaxLimit
+	}
+
+	return limit
+}
+
+// EncodeCursor turns the cursor into an opaque string for clients. A nil cursor means there is no next page.
+func EncodeCursor(cursor *models.Cursor) string {
+	if cursor == nil {
+		return ""
+	}
+
+	data, err := json.Marshal(cursor)
+	if err != nil {
+		return ""
+	}
+
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// DecodeCursor parses a cursor produced by EncodeCursor. An empty string starts from the first page.
+func DecodeCursor(cursor string) (*models.Cursor, error) {
+	if cursor == "" {
+		return nil, nil
+	}
+
+	data, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+
+	var decoded models.Cursor
+
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		return nil, ErrInvalidCursor
+	}
+
+	return &decoded, nil
+}
+
+// TrimPage cuts the extra row requested with LIMIT limit+1 and returns the cursor of the next page, if any.
+func TrimPage[T any](items []T, limit int, cursorOf func(T) models.Cursor) ([]T, *models.Cursor) {
+	if len(items) <= limit {
+		return items, nil
+	}
+
+	items = items[:limit]
+	next := cursorOf(items[limit-1])
+
+	return items, &next
+}
+
+func NewPage[T any](items []T, next *models.Cursor) *models.Page[T] {
+	if items == nil {
+		items = []T{}
+	}
+
+	return &models.Page[T]{
+		Items:      items,
+		NextCursor: EncodeCursor(next),
+	}
+}
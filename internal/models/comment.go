@@ -3,9 +3,24 @@ package models
 import "time"
 
 type Comments struct {
-	ID        string    `json:"id"`
-	PostID    string    `json:"post_id"`
-	UserID    string    `json:"user_id"`
-	Text      string    `json:"text"`
-	CreatedAt time.Time `json:"created_at"`
+	ID         int       `json:"id"`
+	PostID     int       `json:"post_id"`
+	UserID     int       `json:"user_id"`
+	ParentID   *int      `json:"parent_id"`
+	Content    string    `json:"content"`
+	ReplyCount int       `json:"reply_count"`
+	Deleted    bool      `json:"deleted"`
+	CreatedAt  time.Time `json:"created_at"`
+	UpdatedAt  time.Time `json:"updated_at"`
+}
+
+type CreateCommentRequest struct {
+	PostID   int    `json:"post_id"`
+	UserID   int    `json:"user_id"`
+	ParentID *int   `json:"parent_id,omitempty"`
+	Content  string `json:"content" validate:"required,max=2200"`
+}
+
+type UpdateCommentRequest struct {
+	Content string `json:"content" validate:"required,max=2200"`
 }
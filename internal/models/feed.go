@@ -0,0 +1,17 @@
+package models
+
+import "time"
+
+type FeedItem struct {
+	ID             int        `json:"id"`
+	UserID         int        `json:"user_id"`
+	Username       string     `json:"username"`
+	ProfilePic     string     `json:"profile_pic"`
+	ProfilePicURLs *PhotoURLs `json:"profile_pic_urls,omitempty"`
+	ImageURL       string     `json:"image_url"`
+	Images         *PhotoURLs `json:"images,omitempty"`
+	Caption        string     `json:"caption"`
+	LikeCount      int        `json:"like_count"`
+	LikedByMe      bool       `json:"liked_by_me"`
+	CreatedAt      time.Time  `json:"created_at"`
+}
@@ -0,0 +1,53 @@
+package models
+
+import "time"
+
+// Notification types.
+const (
+	NotificationLike    = "like"
+	NotificationFollow  = "follow"
+	NotificationComment = "comment"
+	NotificationReply   = "reply"
+)
+
+// Notification groups events of one type about one post, for example all likes of a post since the
+// recipient last read the notification.
+type Notification struct {
+	ID     int    `json:"id"`
+	Type   string `json:"type"`
+	PostID *int   `json:"post_id,omitempty"`
+	// Text is ready to show, for example "alice and 12 others liked your post".
+	Text       string            `json:"text"`
+	LastActor  NotificationActor `json:"last_actor"`
+	ActorCount int               `json:"actor_count"`
+	Read       bool              `json:"read"`
+	// Removed is set by the notification stream for a group left without actors, e.g. after an unlike.
+	Removed bool `json:"removed,omitempty"`
+	// Seq grows with every change of the notifications of the user, the notification stream uses it as the event ID.
+	Seq       int64     `json:"seq"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+type NotificationActor struct {
+	ID             int        `json:"id"`
+	Username       string     `json:"username"`
+	ProfilePic     string     `json:"profile_pic"`
+	ProfilePicURLs *PhotoURLs `json:"profile_pic_urls,omitempty"`
+}
+
+// NotificationUpdatedEvent tells the API instances that a notification group of the user changed.
+type NotificationUpdatedEvent struct {
+	UserID         int   `json:"user_id"`
+	NotificationID int   `json:"notification_id"`
+	Seq            int64 `json:"seq"`
+}
+
+type UnreadCountResponse struct {
+	Count int `json:"count"`
+}
+
+// MarkReadRequest marks the listed notifications read. An empty list marks all of them.
+type MarkReadRequest struct {
+	IDs []int `json:"ids"`
+}
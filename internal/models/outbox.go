@@ -0,0 +1,11 @@
+package models
+
+import "time"
+
+type OutboxMessage struct {
+	ID        int64     `json:"id"`
+	Topic     string    `json:"topic"`
+	Payload   []byte    `json:"payload"`
+	Attempts  int       `json:"attempts"`
+	CreatedAt time.Time `json:"created_at"`
+}
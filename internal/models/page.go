@@ -0,0 +1,14 @@
+package models
+
+import "time"
+
+// Cursor is the keyset position of the last item of a page, the next page starts right after it.
+type Cursor struct {
+	CreatedAt time.Time `json:"t,omitempty"`
+	ID        int       `json:"id"`
+}
+
+type Page[T any] struct {
+	Items      []T    `json:"items"`
+	NextCursor string `json:"next_cursor,omitempty"`
+}
@@ -0,0 +1,8 @@
+package models
+
+// PhotoURLs are the URLs of the renditions of an uploaded photo.
+type PhotoURLs struct {
+	Thumbnail string `json:"thumbnail"`
+	Feed      string `json:"feed"`
+	Full      string `json:"full"`
+}
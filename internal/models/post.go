@@ -3,12 +3,13 @@ package models
 import "time"
 
 type Posts struct {
-	ID        int       `json:"id"`
-	UserID    int       `json:"user_id"`
-	ImageURL  string    `json:"image_url"`
-	Caption   string    `json:"caption"`
-	CreatedAt time.Time `json:"created_at"`
-	UpdatedAt time.Time `json:"updated_at"`
+	ID        int        `json:"id"`
+	UserID    int        `json:"user_id"`
+	ImageURL  string     `json:"image_url"`
+	Images    *PhotoURLs `json:"images,omitempty"`
+	Caption   string     `json:"caption"`
+	CreatedAt time.Time  `json:"created_at"`
+	UpdatedAt time.Time  `json:"updated_at"`
 }
 
 type CreatePostRequest struct {
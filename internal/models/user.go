@@ -17,6 +17,11 @@ type CreateUserRequest struct {
 	Password string `json:"password" validate:"required"`
 }
 
+type LoginRequest struct {
+	Email    string `json:"email" validate:"required,email"`
+	Password string `json:"password" validate:"required"`
+}
+
 type UpdateUserRequest struct {
 	ID       int    `json:"id"`
 	Username string `json:"username,omitempty"`
@@ -25,11 +30,16 @@ type UpdateUserRequest struct {
 }
 
 type GetUserResponse struct {
-	ID         int    `json:"id"`
-	Username   string `json:"username"`
-	Email      string `json:"email"`
-	ProfilePic string `json:"profile_pic"`
-	Bio        string `json:"bio"`
+	ID             int        `json:"id"`
+	Username       string     `json:"username"`
+	Email          string     `json:"email"`
+	ProfilePic     string     `json:"profile_pic"`
+	ProfilePicURLs *PhotoURLs `json:"profile_pic_urls,omitempty"`
+	Bio            string     `json:"bio"`
+	// Counters are maintained by the event processor and may lag behind for a moment.
+	PostsCount     int `json:"posts_count"`
+	FollowersCount int `json:"followers_count"`
+	FollowingCount int `json:"following_count"`
 }
 
 type GetUserValidate struct {
@@ -42,8 +52,10 @@ type GetAllFollowersResponseDB struct {
 }
 
 type GetAllFollowersResponse struct {
-	Username   string `json:"username"`
-	ProfilePic string `json:"profile_pic"`
+	ID             int        `json:"id"`
+	Username       string     `json:"username"`
+	ProfilePic     string     `json:"profile_pic"`
+	ProfilePicURLs *PhotoURLs `json:"profile_pic_urls,omitempty"`
 }
 
 type UserID struct {
@@ -53,3 +65,18 @@ type UserID struct {
 var (
 	ErrEmailValidate = "invalid email"
 )
+
+// UserEvent is the payload of user events. The email and the password are left out.
+type UserEvent struct {
+	ID         int    `json:"id"`
+	Username   string `json:"username"`
+	Bio        string `json:"bio"`
+	ProfilePic string `json:"profile_pic"`
+}
+
+// UserDeletedEvent lists the accounts the deleted user was connected with, their follows are gone with the user.
+type UserDeletedEvent struct {
+	ID        int   `json:"id"`
+	Followers []int `json:"followers"`
+	Following []int `json:"following"`
+}
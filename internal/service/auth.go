@@ -0,0 +1,174 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"kirkagram/internal/lib/jwt"
+	"kirkagram/internal/models"
+	"kirkagram/internal/storage"
+	"log/slog"
+	"time"
+
+	"github.com/go-playground/validator"
+)
+
+type AuthUserService interface {
+	GetByEmail(ctx context.Context, email string) (*models.User, error)
+}
+
+type TokenService interface {
+	SaveRefreshToken(ctx context.Context, token models.RefreshToken) error
+	RotateRefreshToken(ctx context.Context, oldHash string, next models.RefreshToken) (int, error)
+	RevokeFamilyByHash(ctx context.Context, tokenHash string) error
+	RevokeAllByUserID(ctx context.Context, userID int) error
+}
+
+type Auth struct {
+	users      AuthUserService
+	tokens     TokenService
+	log        *slog.Logger
+	secret     string
+	accessTTL  time.Duration
+	refreshTTL time.Duration
+}
+
+func NewAuthService(
+	users AuthUserService,
+	tokens TokenService,
+	log *slog.Logger,
+	secret string,
+	accessTTL time.Duration,
+	refreshTTL time.Duration,
+) *Auth {
+	return &Auth{
+		users:      users,
+		tokens:     tokens,
+		log:        log,
+		secret:     secret,
+		accessTTL:  accessTTL,
+		refreshTTL: refreshTTL,
+	}
+}
+
+func (a *Auth) Login(ctx context.Context, req models.LoginRequest) (*models.TokenPair, error) {
+	const op = "service.Auth.Login"
+
+	err := validator.New().Struct(req)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	user, err := a.users.GetByEmail(ctx, req.Email)
+	if err != nil {
+		if errors.Is(err, storage.ErrUserNotFound) {
+			a.log.Info("login for unknown email", slog.String("email", req.Email))
+
+			return nil, fmt.Errorf("%s: %w", op, storage.ErrInvalidCredentials)
+		}
+
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if !checkPasswordHash(req.Password, user.Password) {
+		a.log.Info("login with incorrect password", slog.Int("userID", user.ID))
+
+		return nil, fmt.Errorf("%s: %w", op, storage.ErrInvalidCredentials)
+	}
+
+	familyID, err := randomString(16, hex.EncodeToString)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	refreshToken, err := randomString(32, base64.RawURLEncoding.EncodeToString)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	err = a.tokens.SaveRefreshToken(ctx, models.RefreshToken{
+		UserID:    user.ID,
+		FamilyID:  familyID,
+		TokenHash: hashToken(refreshToken),
+		ExpiresAt: time.Now().Add(a.refreshTTL),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	accessToken, err := jwt.NewToken(user.ID, a.secret, a.accessTTL)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return &models.TokenPair{AccessToken: accessToken, RefreshToken: refreshToken}, nil
+}
+
+// Refresh exchanges a refresh token for a new token pair. The presented token can be used only once.
+func (a *Auth) Refresh(ctx context.Context, req models.RefreshRequest) (*models.TokenPair, error) {
+	const op = "service.Auth.Refresh"
+
+	err := validator.New().Struct(req)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	refreshToken, err := randomString(32, base64.RawURLEncoding.EncodeToString)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	userID, err := a.tokens.RotateRefreshToken(ctx, hashToken(req.RefreshToken), models.RefreshToken{
+		TokenHash: hashToken(refreshToken),
+		ExpiresAt: time.Now().Add(a.refreshTTL),
+	})
+	if err != nil {
+		if errors.Is(err, storage.ErrRefreshTokenReused) {
+			a.log.Warn("refresh token reuse detected, token family revoked")
+		}
+
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	accessToken, err := jwt.NewToken(userID, a.secret, a.accessTTL)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return &models.TokenPair{AccessToken: accessToken, RefreshToken: refreshToken}, nil
+}
+
+func (a *Auth) Logout(ctx context.Context, req models.RefreshRequest) error {
+	const op = "service.Auth.Logout"
+
+	err := validator.New().Struct(req)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return a.tokens.RevokeFamilyByHash(ctx, hashToken(req.RefreshToken))
+}
+
+func (a *Auth) LogoutAll(ctx context.Context, userID int) error {
+	return a.tokens.RevokeAllByUserID(ctx, userID)
+}
+
+func randomString(size int, encode func([]byte) string) (string, error) {
+	buf := make([]byte, size)
+
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+
+	return encode(buf), nil
+}
+
+func hashToken(token string) string {
+	hash := sha256.Sum256([]byte(token))
+
+	return hex.EncodeToString(hash[:])
+}
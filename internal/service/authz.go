@@ -0,0 +1,16 @@
+package service
+
+import (
+	"errors"
+)
+
+var ErrForbidden = errors.New("Forbidden")
+
+// authorize checks that the authenticated caller owns the resource belonging to ownerID.
+func authorize(callerID int, ownerID int) error {
+	if callerID != ownerID {
+		return ErrForbidden
+	}
+
+	return nil
+}
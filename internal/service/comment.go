@@ -0,0 +1,154 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"kirkagram/internal/lib/pagination"
+	"kirkagram/internal/models"
+	"kirkagram/internal/storage"
+	"log/slog"
+
+	"github.com/go-playground/validator"
+)
+
+type CommentService interface {
+	CreateComment(ctx context.Context, req models.CreateCommentRequest) (*models.Comments, error)
+	GetCommentByID(ctx context.Context, ID int) (*models.Comments, error)
+	GetCommentsByPostID(ctx context.Context, postID int, after *models.Cursor, limit int) ([]models.Comments, *models.Cursor, error)
+	GetRepliesByCommentID(ctx context.Context, parentID int, after *models.Cursor, limit int) ([]models.Comments, *models.Cursor, error)
+	UpdateComment(ctx context.Context, ID int, content string) error
+	DeleteComment(ctx context.Context, ID int) error
+}
+
+type CommentPostService interface {
+	GetPostByID(ctx context.Context, ID int64) (*models.Posts, error)
+}
+
+type Comment struct {
+	storage CommentService
+	posts   CommentPostService
+	log     *slog.Logger
+}
+
+func NewCommentService(storage CommentService, posts CommentPostService, log *slog.Logger) *Comment {
+	return &Comment{
+		storage: storage,
+		posts:   posts,
+		log:     log,
+	}
+}
+
+func (c *Comment) CreateComment(ctx context.Context, callerID int, req models.CreateCommentRequest) (*models.Comments, error) {
+	const op = "service.comment.CreateComment"
+
+	req.UserID = callerID
+
+	if err := validator.New().Struct(req); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if req.ParentID != nil {
+		parent, err := c.storage.GetCommentByID(ctx, *req.ParentID)
+		if err != nil {
+			return nil, err
+		}
+
+		if parent.Deleted {
+			return nil, fmt.Errorf("%s: %w", op, storage.ErrCommentNotFound)
+		}
+
+		if parent.PostID != req.PostID {
+			return nil, fmt.Errorf("%s: %w", op, storage.ErrCommentParentMismatch)
+		}
+
+		// Threads are one level deep, a reply to a reply joins the thread of the top-level comment.
+		if parent.ParentID != nil {
+			req.ParentID = parent.ParentID
+		}
+	}
+
+	return c.storage.CreateComment(ctx, req)
+}
+
+func (c *Comment) GetCommentsByPostID(ctx context.Context, postID int, cursor string, limit int) (*models.Page[models.Comments], error) {
+	const op = "service.comment.GetCommentsByPostID"
+
+	after, err := pagination.DecodeCursor(cursor)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	comments, next, err := c.storage.GetCommentsByPostID(ctx, postID, after, pagination.Limit(limit))
+	if err != nil {
+		return nil, err
+	}
+
+	return pagination.NewPage(comments, next), nil
+}
+
+func (c *Comment) GetReplies(ctx context.Context, commentID int, cursor string, limit int) (*models.Page[models.Comments], error) {
+	const op = "service.comment.GetReplies"
+
+	after, err := pagination.DecodeCursor(cursor)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if _, err := c.storage.GetCommentByID(ctx, commentID); err != nil {
+		return nil, err
+	}
+
+	replies, next, err := c.storage.GetRepliesByCommentID(ctx, commentID, after, pagination.Limit(limit))
+	if err != nil {
+		return nil, err
+	}
+
+	return pagination.NewPage(replies, next), nil
+}
+
+func (c *Comment) UpdateComment(ctx context.Context, callerID int, ID int, req models.UpdateCommentRequest) error {
+	const op = "service.comment.UpdateComment"
+
+	if err := validator.New().Struct(req); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	comment, err := c.storage.GetCommentByID(ctx, ID)
+	if err != nil {
+		return err
+	}
+
+	if err := authorize(callerID, comment.UserID); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return c.storage.UpdateComment(ctx, ID, req.Content)
+}
+
+// DeleteComment removes the comment if the caller wrote it or owns the post it was left under.
+// A comment with replies is left as a tombstone so the thread stays readable.
+func (c *Comment) DeleteComment(ctx context.Context, callerID int, ID int) error {
+	const op = "service.comment.DeleteComment"
+
+	comment, err := c.storage.GetCommentByID(ctx, ID)
+	if err != nil {
+		return err
+	}
+
+	if comment.Deleted {
+		return fmt.Errorf("%s: %w", op, storage.ErrCommentNotFound)
+	}
+
+	if err := authorize(callerID, comment.UserID); err != nil {
+		post, postErr := c.posts.GetPostByID(ctx, int64(comment.PostID))
+		if postErr != nil {
+			return fmt.Errorf("%s: %w", op, postErr)
+		}
+
+		if err := authorize(callerID, post.UserID); err != nil {
+			return fmt.Errorf("%s: %w", op, err)
+		}
+	}
+
+	return c.storage.DeleteComment(ctx, ID)
+}
@@ -0,0 +1,47 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"kirkagram/internal/lib/pagination"
+	"kirkagram/internal/models"
+	"log/slog"
+)
+
+type FeedService interface {
+	GetFeed(ctx context.Context, userID int, after *models.Cursor, limit int) ([]models.FeedItem, *models.Cursor, error)
+}
+
+type Feed struct {
+	storage FeedService
+	log     *slog.Logger
+}
+
+func NewFeedService(storage FeedService, log *slog.Logger) *Feed {
+	return &Feed{
+		storage: storage,
+		log:     log,
+	}
+}
+
+// GetFeed returns a page of the home feed of userID. An empty cursor starts from the newest post.
+func (f *Feed) GetFeed(ctx context.Context, userID int, cursor string, limit int) (*models.Page[models.FeedItem], error) {
+	const op = "service.feed.GetFeed"
+
+	after, err := pagination.DecodeCursor(cursor)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	items, next, err := f.storage.GetFeed(ctx, userID, after, pagination.Limit(limit))
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range items {
+		items[i].Images = photoURLs(items[i].ImageURL)
+		items[i].ProfilePicURLs = photoURLs(items[i].ProfilePic)
+	}
+
+	return pagination.NewPage(items, next), nil
+}
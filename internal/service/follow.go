@@ -1,68 +1,53 @@
 package service
 
 import (
-	"encoding/json"
+	"context"
 	"fmt"
-	k "kirkagram/internal/kafka"
 	"kirkagram/internal/models"
 	"log/slog"
 )
 
 type FollowService interface {
-	FollowByID(req models.FollowRequest) error
-	UnFollowByID(req models.FollowRequest) error
+	FollowByID(ctx context.Context, req models.FollowRequest) error
+	UnFollowByID(ctx context.Context, req models.FollowRequest) error
 }
 
 type Follow struct {
-	client   FollowService
-	producer k.Producer
-	log      *slog.Logger
+	client FollowService
+	log    *slog.Logger
 }
 
-func NewFollowService(client FollowService, producer k.Producer, log *slog.Logger) *Follow {
+func NewFollowService(client FollowService, log *slog.Logger) *Follow {
 	return &Follow{
-		client:   client,
-		producer: producer,
-		log:      log,
+		client: client,
+		log:    log,
 	}
 }
 
-func (f *Follow) FollowByID(req models.FollowRequest) error {
+func (f *Follow) FollowByID(ctx context.Context, callerID int, req models.FollowRequest) error {
 	const op = "service.follow.FollowByID"
 
-	if err := f.client.FollowByID(req); err != nil {
-		return err
+	if req.FollowerID == 0 {
+		req.FollowerID = callerID
 	}
 
-	followReqSlc, err := json.Marshal(req)
-	if err != nil {
+	if err := authorize(callerID, req.FollowerID); err != nil {
 		return fmt.Errorf("%s: %w", op, err)
 	}
 
-	err = f.producer.Produce(followReqSlc, "follow")
-	if err != nil {
-		return fmt.Errorf("%s: %w", op, err)
-	}
-
-	return nil
+	return f.client.FollowByID(ctx, req)
 }
 
-func (f *Follow) UnFollowByID(req models.FollowRequest) error {
+func (f *Follow) UnFollowByID(ctx context.Context, callerID int, req models.FollowRequest) error {
 	const op = "service.follow.UnFollowByID"
 
-	if err := f.client.UnFollowByID(req); err != nil {
-		return err
-	}
-
-	followReqSlc, err := json.Marshal(req)
-	if err != nil {
-		return fmt.Errorf("%s: %w", op, err)
+	if req.FollowerID == 0 {
+		req.FollowerID = callerID
 	}
 
-	err = f.producer.Produce(followReqSlc, "unfollow")
-	if err != nil {
+	if err := authorize(callerID, req.FollowerID); err != nil {
 		return fmt.Errorf("%s: %w", op, err)
 	}
 
-	return nil
+	return f.client.UnFollowByID(ctx, req)
 }
@@ -1,57 +1,39 @@
 package service
 
 import (
-	"encoding/json"
-	"fmt"
-	k "kirkagram/internal/kafka"
+	"context"
 	"kirkagram/internal/models"
 	"log/slog"
 )
 
 type LikeService interface {
-	LikePostByID(likeReq *models.LikeRequest) error
-	UnlikePostByID(likeReq *models.LikeRequest) error
-	GetLikesByID(postID int) (models.LikeResponse, error)
+	LikePostByID(ctx context.Context, likeReq *models.LikeRequest) error
+	UnlikePostByID(ctx context.Context, likeReq *models.LikeRequest) error
+	GetLikesByID(ctx context.Context, postID int) (models.LikeResponse, error)
 }
 
 type Like struct {
-	client   LikeService
-	producer k.Producer
-	log      *slog.Logger
+	client LikeService
+	log    *slog.Logger
 }
 
-func NewLikeService(client LikeService, producer k.Producer, log *slog.Logger) *Like {
+func NewLikeService(client LikeService, log *slog.Logger) *Like {
 	return &Like{
-		client:   client,
-		producer: producer,
-		log:      log,
+		client: client,
+		log:    log,
 	}
 }
 
-func (l *Like) UnlikePostByID(likeReq *models.LikeRequest) error {
-	return l.client.UnlikePostByID(likeReq)
+// UnlikePostByID removes the like, the unlike event is written to the outbox in the same transaction.
+func (l *Like) UnlikePostByID(ctx context.Context, likeReq *models.LikeRequest) error {
+	return l.client.UnlikePostByID(ctx, likeReq)
 }
 
-func (l *Like) GetLikesByID(postID int) (models.LikeResponse, error) {
-	return l.client.GetLikesByID(postID)
+func (l *Like) GetLikesByID(ctx context.Context, postID int) (models.LikeResponse, error) {
+	return l.client.GetLikesByID(ctx, postID)
 }
 
-func (l *Like) LikePostByID(likeReq *models.LikeRequest) error {
-	const op = "service.like.LikePostByID"
-
-	if err := l.client.LikePostByID(likeReq); err != nil {
-		return err
-	}
-
-	likeReqSlc, err := json.Marshal(likeReq)
-	if err != nil {
-		return fmt.Errorf("%s: %w", op, err)
-	}
-
-	err = l.producer.Produce(likeReqSlc, "like")
-	if err != nil {
-		return fmt.Errorf("%s: %w", op, err)
-	}
-
-	return nil
+// LikePostByID stores the like, the like event is written to the outbox in the same transaction.
+func (l *Like) LikePostByID(ctx context.Context, likeReq *models.LikeRequest) error {
+	return l.client.LikePostByID(ctx, likeReq)
 }
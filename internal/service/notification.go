@@ -0,0 +1,122 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"kirkagram/internal/lib/pagination"
+	"kirkagram/internal/models"
+	"log/slog"
+)
+
+type NotificationService interface {
+	GetNotifications(ctx context.Context, userID int, after *models.Cursor, limit int) ([]models.Notification, *models.Cursor, error)
+	GetUnreadCount(ctx context.Context, userID int) (int, error)
+	MarkRead(ctx context.Context, userID int, IDs []int) (int64, error)
+	GetNotificationsAfter(ctx context.Context, userID int, seq int64, limit int) ([]models.Notification, error)
+	GetLatestSeq(ctx context.Context, userID int) (int64, error)
+}
+
+type Notification struct {
+	storage NotificationService
+	log     *slog.Logger
+}
+
+func NewNotificationService(storage NotificationService, log *slog.Logger) *Notification {
+	return &Notification{
+		storage: storage,
+		log:     log,
+	}
+}
+
+// GetNotifications returns a page of the notifications of userID, the most recent activity first.
+func (n *Notification) GetNotifications(ctx context.Context, userID int, cursor string, limit int) (*models.Page[models.Notification], error) {
+	const op = "service.notification.GetNotifications"
+
+	after, err := pagination.DecodeCursor(cursor)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	notifications, next, err := n.storage.GetNotifications(ctx, userID, after, pagination.Limit(limit))
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range notifications {
+		notifications[i].Text = notificationText(notifications[i])
+		notifications[i].LastActor.ProfilePicURLs = photoURLs(notifications[i].LastActor.ProfilePic)
+	}
+
+	return pagination.NewPage(notifications, next), nil
+}
+
+// GetNotificationsAfter returns the notifications of userID changed after seq, the oldest change first.
+// A group left without actors is returned as removed.
+func (n *Notification) GetNotificationsAfter(ctx context.Context, userID int, seq int64, limit int) ([]models.Notification, error) {
+	notifications, err := n.storage.GetNotificationsAfter(ctx, userID, seq, pagination.Limit(limit))
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range notifications {
+		if notifications[i].ActorCount == 0 {
+			notifications[i].Removed = true
+
+			continue
+		}
+
+		notifications[i].Text = notificationText(notifications[i])
+		notifications[i].LastActor.ProfilePicURLs = photoURLs(notifications[i].LastActor.ProfilePic)
+	}
+
+	return notifications, nil
+}
+
+func (n *Notification) GetLatestSeq(ctx context.Context, userID int) (int64, error) {
+	return n.storage.GetLatestSeq(ctx, userID)
+}
+
+func (n *Notification) GetUnreadCount(ctx context.Context, userID int) (*models.UnreadCountResponse, error) {
+	count, err := n.storage.GetUnreadCount(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.UnreadCountResponse{Count: count}, nil
+}
+
+func (n *Notification) MarkRead(ctx context.Context, userID int, req models.MarkReadRequest) error {
+	num, err := n.storage.MarkRead(ctx, userID, req.IDs)
+	if err != nil {
+		return err
+	}
+
+	n.log.Info("notifications marked read", slog.Int("userID", userID), slog.Int64("notifications", num))
+
+	return nil
+}
+
+// notificationText renders the group as "alice and 12 others liked your post".
+func notificationText(notification models.Notification) string {
+	actors := notification.LastActor.Username
+
+	switch others := notification.ActorCount - 1; {
+	case others == 1:
+		actors += " and 1 other"
+	case others > 1:
+		actors += fmt.Sprintf(" and %d others", others)
+	}
+
+	switch notification.Type {
+	case models.NotificationLike:
+		return actors + " liked your post"
+	case models.NotificationFollow:
+		return actors + " started following you"
+	case models.NotificationComment:
+		return actors + " commented on your post"
+	case models.NotificationReply:
+		return actors + " replied to your comment"
+	}
+
+	return actors
+}
@@ -0,0 +1,158 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"kirkagram/internal/models"
+	"kirkagram/internal/storage"
+	"log/slog"
+)
+
+type NotifierService interface {
+	AddActor(ctx context.Context, userID int, notificationType string, postID *int, actorID int) error
+	RemoveActor(ctx context.Context, userID int, notificationType string, postID *int, actorID int) error
+}
+
+type NotifierPostService interface {
+	GetPostByID(ctx context.Context, ID int64) (*models.Posts, error)
+}
+
+type NotifierCommentService interface {
+	GetCommentByID(ctx context.Context, ID int) (*models.Comments, error)
+}
+
+// Notifier turns like, follow and comment events into notifications of the users they concern.
+// Nobody is notified about their own actions, and events about deleted posts are skipped.
+type Notifier struct {
+	storage  NotifierService
+	posts    NotifierPostService
+	comments NotifierCommentService
+	log      *slog.Logger
+}
+
+func NewNotifierService(storage NotifierService, posts NotifierPostService, comments NotifierCommentService, log *slog.Logger) *Notifier {
+	return &Notifier{
+		storage:  storage,
+		posts:    posts,
+		comments: comments,
+		log:      log,
+	}
+}
+
+func (n *Notifier) Liked(ctx context.Context, req models.LikeRequest) error {
+	const op = "service.notifier.Liked"
+
+	if err := n.notifyPostAuthor(ctx, models.NotificationLike, req.PostID, req.UserID); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+func (n *Notifier) Unliked(ctx context.Context, req models.LikeRequest) error {
+	const op = "service.notifier.Unliked"
+
+	post, err := n.posts.GetPostByID(ctx, int64(req.PostID))
+	if err != nil {
+		if errors.Is(err, storage.ErrPostNotFound) {
+			return nil
+		}
+
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := n.storage.RemoveActor(ctx, post.UserID, models.NotificationLike, &post.ID, req.UserID); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+func (n *Notifier) Followed(ctx context.Context, req models.FollowRequest) error {
+	const op = "service.notifier.Followed"
+
+	if req.FollowerID == req.FollowingID {
+		return nil
+	}
+
+	if err := n.storage.AddActor(ctx, req.FollowingID, models.NotificationFollow, nil, req.FollowerID); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	n.log.Info("follow notification added", slog.Int("userID", req.FollowingID), slog.Int("actorID", req.FollowerID))
+
+	return nil
+}
+
+func (n *Notifier) Unfollowed(ctx context.Context, req models.FollowRequest) error {
+	const op = "service.notifier.Unfollowed"
+
+	if err := n.storage.RemoveActor(ctx, req.FollowingID, models.NotificationFollow, nil, req.FollowerID); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+// Commented notifies the author of the post and, for a reply, the author of the parent comment.
+func (n *Notifier) Commented(ctx context.Context, comment models.Comments) error {
+	const op = "service.notifier.Commented"
+
+	if err := n.notifyPostAuthor(ctx, models.NotificationComment, comment.PostID, comment.UserID); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if comment.ParentID == nil {
+		return nil
+	}
+
+	parent, err := n.comments.GetCommentByID(ctx, *comment.ParentID)
+	if err != nil {
+		if errors.Is(err, storage.ErrCommentNotFound) {
+			return nil
+		}
+
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if parent.Deleted || parent.UserID == comment.UserID {
+		return nil
+	}
+
+	if err := n.storage.AddActor(ctx, parent.UserID, models.NotificationReply, &comment.PostID, comment.UserID); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	n.log.Info("reply notification added", slog.Int("userID", parent.UserID), slog.Int("actorID", comment.UserID))
+
+	return nil
+}
+
+func (n *Notifier) notifyPostAuthor(ctx context.Context, notificationType string, postID int, actorID int) error {
+	post, err := n.posts.GetPostByID(ctx, int64(postID))
+	if err != nil {
+		if errors.Is(err, storage.ErrPostNotFound) {
+			return nil
+		}
+
+		return err
+	}
+
+	if post.UserID == actorID {
+		return nil
+	}
+
+	if err := n.storage.AddActor(ctx, post.UserID, notificationType, &post.ID, actorID); err != nil {
+		return err
+	}
+
+	n.log.Info(
+		"notification added",
+		slog.String("type", notificationType),
+		slog.Int("userID", post.UserID),
+		slog.Int("actorID", actorID),
+	)
+
+	return nil
+}
@@ -0,0 +1,230 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	k "kirkagram/internal/kafka"
+	"kirkagram/internal/models"
+	"kirkagram/internal/storage"
+	"kirkagram/internal/transport/consumer"
+	"reflect"
+	"strconv"
+	"sync"
+	"testing"
+)
+
+type notifierCall struct {
+	method  string
+	userID  int
+	kind    string
+	postID  int
+	actorID int
+}
+
+// memNotifications records the calls of the notifier. It fails the first fail calls.
+type memNotifications struct {
+	mu    sync.Mutex
+	calls []notifierCall
+	fail  int
+}
+
+func (s *memNotifications) record(method string, userID int, notificationType string, postID *int, actorID int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.fail > 0 {
+		s.fail--
+
+		return errors.New("storage unavailable")
+	}
+
+	call := notifierCall{method: method, userID: userID, kind: notificationType, actorID: actorID}
+	if postID != nil {
+		call.postID = *postID
+	}
+
+	s.calls = append(s.calls, call)
+
+	return nil
+}
+
+func (s *memNotifications) AddActor(ctx context.Context, userID int, notificationType string, postID *int, actorID int) error {
+	return s.record("AddActor", userID, notificationType, postID, actorID)
+}
+
+func (s *memNotifications) RemoveActor(ctx context.Context, userID int, notificationType string, postID *int, actorID int) error {
+	return s.record("RemoveActor", userID, notificationType, postID, actorID)
+}
+
+type memPosts map[int]models.Posts
+
+func (p memPosts) GetPostByID(ctx context.Context, ID int64) (*models.Posts, error) {
+	post, ok := p[int(ID)]
+	if !ok {
+		return nil, storage.ErrPostNotFound
+	}
+
+	return &post, nil
+}
+
+type memComments map[int]models.Comments
+
+func (c memComments) GetCommentByID(ctx context.Context, ID int) (*models.Comments, error) {
+	comment, ok := c[ID]
+	if !ok {
+		return nil, storage.ErrCommentNotFound
+	}
+
+	return &comment, nil
+}
+
+type busEvent struct {
+	eventType string
+	actorID   int
+	payload   any
+}
+
+// notify publishes the events on an in-memory bus with the notifier subscribed and returns the calls
+// it made once the bus handled all of them.
+func notify(t *testing.T, notifications *memNotifications, events ...busEvent) []notifierCall {
+	t.Helper()
+
+	posts := memPosts{
+		1: {ID: 1, UserID: 10},
+	}
+	comments := memComments{
+		5: {ID: 5, PostID: 1, UserID: 20},
+	}
+
+	bus := k.NewBus(testLogger())
+	consumer.RegisterNotifications(bus.Group("notifier"), NewNotifierService(notifications, posts, comments, testLogger()))
+
+	done := make(chan struct{})
+
+	go func() {
+		defer close(done)
+
+		bus.Run(context.Background())
+	}()
+
+	var publisher k.Publisher = bus
+
+	for _, e := range events {
+		event, err := k.NewEvent(e.eventType, e.actorID, strconv.Itoa(e.actorID), e.payload)
+		if err != nil {
+			t.Fatalf("NewEvent: %v", err)
+		}
+
+		topic, err := event.Topic()
+		if err != nil {
+			t.Fatalf("Topic: %v", err)
+		}
+
+		value, err := json.Marshal(event)
+		if err != nil {
+			t.Fatalf("marshal: %v", err)
+		}
+
+		if err := publisher.ProduceEvent(topic, value); err != nil {
+			t.Fatalf("ProduceEvent: %v", err)
+		}
+	}
+
+	bus.Close()
+	<-done
+
+	notifications.mu.Lock()
+	defer notifications.mu.Unlock()
+
+	return notifications.calls
+}
+
+func TestNotifier(t *testing.T) {
+	parentID := 5
+
+	tests := []struct {
+		name   string
+		events []busEvent
+		want   []notifierCall
+	}{
+		{
+			name:   "like notifies the post author",
+			events: []busEvent{{k.LikeCreated, 30, models.LikeRequest{UserID: 30, PostID: 1}}},
+			want:   []notifierCall{{"AddActor", 10, models.NotificationLike, 1, 30}},
+		},
+		{
+			name:   "own like is skipped",
+			events: []busEvent{{k.LikeCreated, 10, models.LikeRequest{UserID: 10, PostID: 1}}},
+		},
+		{
+			name:   "like of a deleted post is skipped",
+			events: []busEvent{{k.LikeCreated, 30, models.LikeRequest{UserID: 30, PostID: 2}}},
+		},
+		{
+			name: "unlike removes the actor",
+			events: []busEvent{
+				{k.LikeCreated, 30, models.LikeRequest{UserID: 30, PostID: 1}},
+				{k.LikeDeleted, 30, models.LikeRequest{UserID: 30, PostID: 1}},
+			},
+			want: []notifierCall{
+				{"AddActor", 10, models.NotificationLike, 1, 30},
+				{"RemoveActor", 10, models.NotificationLike, 1, 30},
+			},
+		},
+		{
+			name: "follow and unfollow",
+			events: []busEvent{
+				{k.FollowCreated, 30, models.FollowRequest{FollowerID: 30, FollowingID: 10}},
+				{k.FollowDeleted, 30, models.FollowRequest{FollowerID: 30, FollowingID: 10}},
+			},
+			want: []notifierCall{
+				{"AddActor", 10, models.NotificationFollow, 0, 30},
+				{"RemoveActor", 10, models.NotificationFollow, 0, 30},
+			},
+		},
+		{
+			name:   "reply notifies the post and the parent comment authors",
+			events: []busEvent{{k.CommentCreated, 30, models.Comments{ID: 6, PostID: 1, UserID: 30, ParentID: &parentID}}},
+			want: []notifierCall{
+				{"AddActor", 10, models.NotificationComment, 1, 30},
+				{"AddActor", 20, models.NotificationReply, 1, 30},
+			},
+		},
+		{
+			name:   "reply to own comment notifies only the post author",
+			events: []busEvent{{k.CommentCreated, 20, models.Comments{ID: 6, PostID: 1, UserID: 20, ParentID: &parentID}}},
+			want:   []notifierCall{{"AddActor", 10, models.NotificationComment, 1, 20}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := notify(t, &memNotifications{}, tt.events...)
+
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("calls = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNotifierRetriedByBus(t *testing.T) {
+	like := busEvent{k.LikeCreated, 30, models.LikeRequest{UserID: 30, PostID: 1}}
+
+	// The bus retries a failed event, so two failures still add the notification.
+	got := notify(t, &memNotifications{fail: 2}, like)
+	if len(got) != 1 {
+		t.Errorf("got %d calls after two failures, want 1", len(got))
+	}
+
+	// The third failure drops the event.
+	notifications := &memNotifications{fail: 3}
+	if got := notify(t, notifications, like); len(got) != 0 {
+		t.Errorf("got %d calls after three failures, want 0", len(got))
+	}
+
+	if notifications.fail != 0 {
+		t.Errorf("storage called %d times, want 3", 3-notifications.fail)
+	}
+}
@@ -1,30 +1,199 @@
 package service
 
 import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"github.com/disintegration/imaging"
+	"image"
+	"io"
+	"kirkagram/internal/models"
+	"kirkagram/internal/storage"
 	"log/slog"
+	"net/http"
+	"runtime"
+	"strings"
+
+	_ "golang.org/x/image/webp"
+)
+
+var (
+	ErrUnsupportedImage = errors.New("Unsupported image, JPEG, PNG or WebP is expected")
+	ErrImageTooLarge    = errors.New("Image dimensions are too large")
 )
 
+// photoURLPrefix is the route photos are served from.
+const photoURLPrefix = "/api/photo/"
+
+// Suffixes of the rendition keys, the full size rendition is stored under the photo key itself.
+const (
+	thumbSuffix = "_thumb"
+	feedSuffix  = "_feed"
+)
+
+// sniffLen is the number of bytes http.DetectContentType looks at.
+const sniffLen = 512
+
+// allowedTypes maps the sniffed content types to the format names of the image package.
+var allowedTypes = map[string]string{
+	"image/jpeg": "jpeg",
+	"image/png":  "png",
+	"image/webp": "webp",
+}
+
+// PhotoService streams photos in and out of the storage. The size is -1 when it isn't known.
+// The caller closes the reader returned by GetPhoto.
 type PhotoService interface {
-	GetPhoto(key string) ([]byte, error)
-	UploadPhoto(key string, data []byte) error
+	GetPhoto(ctx context.Context, key string) (io.ReadCloser, int64, error)
+	UploadPhoto(ctx context.Context, key string, body io.Reader, size int64) error
+}
+
+// rendition is a resized copy of an uploaded photo, stored under the photo key with the suffix.
+type rendition struct {
+	suffix string
+	width  int
+	height int
+	// crop fills the whole box, the photo is cut to its aspect ratio instead of fitting inside it.
+	crop bool
+}
+
+// renditions are generated for every upload. The full size one keeps the photo key, so the URLs of
+// posts and profile pictures created before the renditions keep working.
+var renditions = []rendition{
+	{suffix: thumbSuffix, width: 150, height: 150, crop: true},
+	{suffix: feedSuffix, width: 1080, height: 1350},
+	{suffix: "", width: 2048, height: 2048},
 }
 
 type Photo struct {
-	client PhotoService
-	log    *slog.Logger
+	client       PhotoService
+	maxDimension int
+	// processing limits the photos decoded at once, a decoded photo takes 4 bytes per pixel.
+	processing chan struct{}
+	log        *slog.Logger
 }
 
-func NewPhotoService(client PhotoService, log *slog.Logger) *Photo {
+func NewPhotoService(client PhotoService, maxDimension int, log *slog.Logger) *Photo {
 	return &Photo{
-		client: client,
-		log:    log,
+		client:       client,
+		maxDimension: maxDimension,
+		processing:   make(chan struct{}, runtime.GOMAXPROCS(0)),
+		log:          log,
+	}
+}
+
+// UploadPhoto validates the photo, orients it by its EXIF data and stores every rendition.
+// The original isn't kept, so the stored photos carry no EXIF metadata.
+func (p *Photo) UploadPhoto(ctx context.Context, key string, body io.Reader) error {
+	const op = "service.Photo.UploadPhoto"
+
+	select {
+	case p.processing <- struct{}{}:
+	case <-ctx.Done():
+		return fmt.Errorf("%s: %w", op, ctx.Err())
+	}
+	defer func() { <-p.processing }()
+
+	img, format, err := p.decode(body)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	for _, r := range renditions {
+		var buf bytes.Buffer
+
+		if err := encode(&buf, r.resize(img), format); err != nil {
+			return fmt.Errorf("%s: %w", op, err)
+		}
+
+		if err := p.client.UploadPhoto(ctx, key+r.suffix, &buf, int64(buf.Len())); err != nil {
+			return fmt.Errorf("%s: %w", op, err)
+		}
+	}
+
+	p.log.Info("Photo processed", slog.String("key", key), slog.String("format", format))
+
+	return nil
+}
+
+// GetPhoto serves the original for the renditions of photos uploaded before they were generated.
+func (p *Photo) GetPhoto(ctx context.Context, key string) (io.ReadCloser, int64, error) {
+	body, size, err := p.client.GetPhoto(ctx, key)
+	if errors.Is(err, storage.ErrNoSuchKey) {
+		for _, suffix := range []string{thumbSuffix, feedSuffix} {
+			if base, ok := strings.CutSuffix(key, suffix); ok {
+				return p.client.GetPhoto(ctx, base)
+			}
+		}
+	}
+
+	return body, size, err
+}
+
+// decode sniffs the content type and checks the dimensions from the header before the pixels are decoded.
+func (p *Photo) decode(body io.Reader) (image.Image, string, error) {
+	br := bufio.NewReaderSize(body, sniffLen)
+
+	head, err := br.Peek(sniffLen)
+	if err != nil && !errors.Is(err, io.EOF) {
+		return nil, "", err
+	}
+
+	format, ok := allowedTypes[http.DetectContentType(head)]
+	if !ok {
+		return nil, "", ErrUnsupportedImage
+	}
+
+	// The bytes read for the header are replayed to the decoder.
+	var header bytes.Buffer
+
+	cfg, _, err := image.DecodeConfig(io.TeeReader(br, &header))
+	if err != nil {
+		return nil, "", fmt.Errorf("%w: %w", ErrUnsupportedImage, err)
+	}
+
+	if cfg.Width > p.maxDimension || cfg.Height > p.maxDimension {
+		return nil, "", fmt.Errorf("%w: %dx%d, %d at most", ErrImageTooLarge, cfg.Width, cfg.Height, p.maxDimension)
+	}
+
+	img, err := imaging.Decode(io.MultiReader(&header, br), imaging.AutoOrientation(true))
+	if err != nil {
+		return nil, "", fmt.Errorf("%w: %w", ErrUnsupportedImage, err)
 	}
+
+	return img, format, nil
 }
 
-func (p *Photo) UploadPhoto(key string, data []byte) error {
-	return p.client.UploadPhoto(key, data)
+func (r rendition) resize(img image.Image) image.Image {
+	if r.crop {
+		return imaging.Fill(img, r.width, r.height, imaging.Center, imaging.Lanczos)
+	}
+
+	return imaging.Fit(img, r.width, r.height, imaging.Lanczos)
 }
 
-func (p *Photo) GetPhoto(key string) ([]byte, error) {
-	return p.client.GetPhoto(key)
+// encode keeps PNG and transparent photos lossless, the rest is stored as JPEG.
+func encode(w io.Writer, img image.Image, format string) error {
+	if opaque, ok := img.(interface{ Opaque() bool }); format == "png" || (ok && !opaque.Opaque()) {
+		return imaging.Encode(w, img, imaging.PNG)
+	}
+
+	return imaging.Encode(w, img, imaging.JPEG, imaging.JPEGQuality(85))
+}
+
+// photoURLs returns the URLs of the renditions of the photo served from photoURL. Post images are
+// stored as /api/photo/<key> and profile pictures without the leading slash.
+func photoURLs(photoURL string) *models.PhotoURLs {
+	key := strings.TrimPrefix(strings.TrimPrefix(photoURL, "/"), strings.TrimPrefix(photoURLPrefix, "/"))
+	if key == "" {
+		return nil
+	}
+
+	return &models.PhotoURLs{
+		Thumbnail: photoURLPrefix + key + thumbSuffix,
+		Feed:      photoURLPrefix + key + feedSuffix,
+		Full:      photoURLPrefix + key,
+	}
 }
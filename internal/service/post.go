@@ -1,67 +1,102 @@
 package service
 
 import (
-	"encoding/json"
+	"context"
 	"fmt"
-	k "kirkagram/internal/kafka"
+	"kirkagram/internal/lib/pagination"
 	"kirkagram/internal/models"
 	"log/slog"
 )
 
 type PostService interface {
-	CreatePost(post models.CreatePostRequest) error
-	GetAllPosts() (*[]models.Posts, error)
-	GetPostByID(ID int64) (*models.Posts, error)
-	GetAllPostsByUserID(userID int64) (*[]models.Posts, error)
-	DeletePost(ID int64) error
+	CreatePost(ctx context.Context, post models.CreatePostRequest) (*models.Posts, error)
+	GetAllPosts(ctx context.Context, after *models.Cursor, limit int) ([]models.Posts, *models.Cursor, error)
+	GetPostByID(ctx context.Context, ID int64) (*models.Posts, error)
+	GetAllPostsByUserID(ctx context.Context, userID int64, after *models.Cursor, limit int) ([]models.Posts, *models.Cursor, error)
+	DeletePost(ctx context.Context, ID int64) error
 }
 
 type Post struct {
-	storage  PostService
-	producer k.Producer
-	log      *slog.Logger
+	storage PostService
+	log     *slog.Logger
 }
 
-func NewPostService(storage PostService, producer k.Producer, log *slog.Logger) *Post {
+func NewPostService(storage PostService, log *slog.Logger) *Post {
 	return &Post{
-		storage:  storage,
-		producer: producer,
-		log:      log,
+		storage: storage,
+		log:     log,
 	}
 }
 
-func (p *Post) DeletePost(ID int64) error {
-	return p.storage.DeletePost(ID)
-}
+func (p *Post) DeletePost(ctx context.Context, callerID int, ID int64) error {
+	const op = "service.post.DeletePost"
+
+	post, err := p.storage.GetPostByID(ctx, ID)
+	if err != nil {
+		return err
+	}
+
+	if err := authorize(callerID, post.UserID); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
 
-func (p *Post) GetAllPostsByUserID(userID int64) (*[]models.Posts, error) {
-	return p.storage.GetAllPostsByUserID(userID)
+	return p.storage.DeletePost(ctx, ID)
 }
 
-func (p *Post) CreatePost(post models.CreatePostRequest) error {
-	const op = "service.CreatePost"
+func (p *Post) GetAllPostsByUserID(ctx context.Context, userID int64, cursor string, limit int) (*models.Page[models.Posts], error) {
+	const op = "service.GetAllPostsByUserID"
 
-	if err := p.storage.CreatePost(post); err != nil {
-		return err
+	after, err := pagination.DecodeCursor(cursor)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
 	}
 
-	postSlc, err := json.Marshal(post)
+	posts, next, err := p.storage.GetAllPostsByUserID(ctx, userID, after, pagination.Limit(limit))
 	if err != nil {
-		return fmt.Errorf("%s: %w", op, err)
+		return nil, err
 	}
 
-	err = p.producer.Produce(postSlc, "post")
-	if err != nil {
-		return err
+	for i := range posts {
+		posts[i].Images = photoURLs(posts[i].ImageURL)
 	}
 
-	return nil
+	return pagination.NewPage(posts, next), nil
 }
 
-func (p *Post) GetAllPosts() (*[]models.Posts, error) {
-	return p.storage.GetAllPosts()
+// CreatePost stores the post, the post event is written to the outbox in the same transaction.
+func (p *Post) CreatePost(ctx context.Context, post models.CreatePostRequest) error {
+	_, err := p.storage.CreatePost(ctx, post)
+
+	return err
 }
 
-func (p *Post) GetPostByID(ID int64) (*models.Posts, error) {
-	return p.storage.GetPostByID(ID)
+func (p *Post) GetAllPosts(ctx context.Context, cursor string, limit int) (*models.Page[models.Posts], error) {
+	const op = "service.GetAllPosts"
+
+	after, err := pagination.DecodeCursor(cursor)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	posts, next, err := p.storage.GetAllPosts(ctx, after, pagination.Limit(limit))
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range posts {
+		posts[i].Images = photoURLs(posts[i].ImageURL)
+	}
+
+	return pagination.NewPage(posts, next), nil
+}
+
+func (p *Post) GetPostByID(ctx context.Context, ID int64) (*models.Posts, error) {
+	post, err := p.storage.GetPostByID(ctx, ID)
+	if err != nil {
+		return nil, err
+	}
+
+	post.Images = photoURLs(post.ImageURL)
+
+	return post, nil
 }
@@ -0,0 +1,86 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	k "kirkagram/internal/kafka"
+	"kirkagram/internal/models"
+	"kirkagram/internal/storage"
+	"log/slog"
+	"time"
+)
+
+const (
+	relayBatchSize    = 100
+	relayPollInterval = time.Second
+	relayMaxBackoff   = 30 * time.Second
+	// relayMaxAttempts parks a message that keeps failing. At the maximum backoff it is about
+	// 50 minutes, so a broker outage doesn't park the events waiting for it.
+	relayMaxAttempts = 100
+)
+
+type OutboxService interface {
+	PublishPending(ctx context.Context, limit int, maxAttempts int, publish func(msg models.OutboxMessage) error) (int, int, error)
+}
+
+// Relay moves events from the outbox to Kafka or the in-memory bus. Every event is published at least once,
+// a message is removed from the outbox only after the publisher accepted it.
+type Relay struct {
+	storage   OutboxService
+	publisher k.Publisher
+	log       *slog.Logger
+}
+
+func NewRelayService(storage OutboxService, publisher k.Publisher, log *slog.Logger) *Relay {
+	return &Relay{
+		storage:   storage,
+		publisher: publisher,
+		log:       log,
+	}
+}
+
+// Run publishes pending events until ctx is canceled. Failures are retried with exponential backoff.
+func (r *Relay) Run(ctx context.Context) {
+	backoff := relayPollInterval
+
+	for {
+		num, parked, err := r.storage.PublishPending(ctx, relayBatchSize, relayMaxAttempts, func(msg models.OutboxMessage) error {
+			err := r.publisher.ProduceEvent(msg.Topic, msg.Payload)
+			if err != nil && k.IsPermanent(err) {
+				return fmt.Errorf("%w: %w", storage.ErrUnpublishable, err)
+			}
+
+			return err
+		})
+
+		if parked > 0 {
+			r.log.Error("outbox messages parked, they won't be published until parked_at is cleared", slog.Int("messages", parked))
+		}
+
+		wait := relayPollInterval
+
+		switch {
+		case err != nil:
+			r.log.Error("unable to publish outbox", slog.Int("published", num), slog.String("error", err.Error()))
+
+			wait = backoff
+			backoff = min(backoff*2, relayMaxBackoff)
+		case num+parked == relayBatchSize:
+			// The outbox has more pending messages, the next batch is taken right away.
+			wait = 0
+			backoff = relayPollInterval
+		default:
+			backoff = relayPollInterval
+		}
+
+		if num > 0 {
+			r.log.Debug("outbox published", slog.Int("messages", num))
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(wait):
+		}
+	}
+}
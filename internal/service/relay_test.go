@@ -0,0 +1,182 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	k "kirkagram/internal/kafka"
+	"kirkagram/internal/models"
+	"kirkagram/internal/storage"
+	"log/slog"
+	"sync"
+	"testing"
+	"time"
+)
+
+func testLogger() *slog.Logger {
+	return slog.New(slog.NewTextHandler(io.Discard, nil))
+}
+
+// memOutbox keeps the outbox in memory and parks messages like the Postgres outbox does.
+type memOutbox struct {
+	mu      sync.Mutex
+	pending []models.OutboxMessage
+	parked  []models.OutboxMessage
+}
+
+func (o *memOutbox) add(t *testing.T, eventType string, key string, payload any) {
+	t.Helper()
+
+	event, err := k.NewEvent(eventType, 1, key, payload)
+	if err != nil {
+		t.Fatalf("NewEvent: %v", err)
+	}
+
+	o.addEvent(t, event)
+}
+
+func (o *memOutbox) addEvent(t *testing.T, event *k.Event) {
+	t.Helper()
+
+	value, err := json.Marshal(event)
+	if err != nil {
+		t.Fatalf("marshal: %v", err)
+	}
+
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	o.pending = append(o.pending, models.OutboxMessage{
+		ID:        int64(len(o.pending) + len(o.parked) + 1),
+		Topic:     k.TopicPost,
+		Payload:   value,
+		CreatedAt: time.Now(),
+	})
+}
+
+func (o *memOutbox) PublishPending(ctx context.Context, limit int, maxAttempts int, publish func(msg models.OutboxMessage) error) (int, int, error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	published, parked := 0, 0
+
+	for len(o.pending) > 0 && published+parked < limit {
+		msg := o.pending[0]
+
+		if err := publish(msg); err != nil {
+			if !errors.Is(err, storage.ErrUnpublishable) && msg.Attempts+1 < maxAttempts {
+				o.pending[0].Attempts++
+
+				return published, parked, err
+			}
+
+			o.parked = append(o.parked, msg)
+			parked++
+		} else {
+			published++
+		}
+
+		o.pending = o.pending[1:]
+	}
+
+	return published, parked, nil
+}
+
+func (o *memOutbox) counts() (int, int) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	return len(o.pending), len(o.parked)
+}
+
+// startBus runs the bus until the test ends.
+func startBus(t *testing.T, bus *k.Bus) {
+	t.Helper()
+
+	done := make(chan struct{})
+
+	go func() {
+		defer close(done)
+
+		bus.Run(context.Background())
+	}()
+
+	t.Cleanup(func() {
+		bus.Close()
+		<-done
+	})
+}
+
+func TestRelayPublishesOutbox(t *testing.T) {
+	bus := k.NewBus(testLogger())
+
+	received := make(chan int, 3)
+	k.HandleEvent(bus.Group("test"), k.PostCreated, func(ctx context.Context, event *k.Event, post models.Posts) error {
+		received <- post.ID
+
+		return nil
+	})
+
+	startBus(t, bus)
+
+	outbox := &memOutbox{}
+	outbox.add(t, k.PostCreated, "1", models.Posts{ID: 1})
+
+	// An event this build can't read is parked instead of blocking the ones behind it.
+	newer, err := k.NewEvent(k.PostCreated, 1, "2", models.Posts{ID: 2})
+	if err != nil {
+		t.Fatalf("NewEvent: %v", err)
+	}
+	newer.Version++
+	outbox.addEvent(t, newer)
+
+	outbox.add(t, k.PostCreated, "3", models.Posts{ID: 3})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	stopped := make(chan struct{})
+
+	go func() {
+		defer close(stopped)
+
+		NewRelayService(outbox, bus, testLogger()).Run(ctx)
+	}()
+
+	defer func() {
+		cancel()
+		<-stopped
+	}()
+
+	for _, want := range []int{1, 3} {
+		select {
+		case got := <-received:
+			if got != want {
+				t.Fatalf("received post %d, want %d", got, want)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatalf("post %d wasn't delivered", want)
+		}
+	}
+
+	if pending, parked := outbox.counts(); pending != 0 || parked != 1 {
+		t.Errorf("pending %d, parked %d, want 0 and 1", pending, parked)
+	}
+}
+
+func TestRelayKeepsOutboxWhenBusClosed(t *testing.T) {
+	bus := k.NewBus(testLogger())
+	bus.Close()
+
+	outbox := &memOutbox{}
+	outbox.add(t, k.PostCreated, "1", models.Posts{ID: 1})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+
+	NewRelayService(outbox, bus, testLogger()).Run(ctx)
+
+	// A closed bus is a transient failure: the message stays pending for the next run.
+	if pending, parked := outbox.counts(); pending != 1 || parked != 0 {
+		t.Errorf("pending %d, parked %d, want 1 and 0", pending, parked)
+	}
+}
@@ -0,0 +1,108 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"kirkagram/internal/models"
+	"log/slog"
+)
+
+type StatsService interface {
+	RefreshUserStats(ctx context.Context, userID int) error
+}
+
+// Stats keeps the post and follow counters shown on user profiles.
+type Stats struct {
+	storage StatsService
+	log     *slog.Logger
+}
+
+func NewStatsService(storage StatsService, log *slog.Logger) *Stats {
+	return &Stats{
+		storage: storage,
+		log:     log,
+	}
+}
+
+func (s *Stats) PostCreated(ctx context.Context, post models.Posts) error {
+	const op = "service.stats.PostCreated"
+
+	if err := s.storage.RefreshUserStats(ctx, post.UserID); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	s.log.Info("user stats refreshed", slog.Int("userID", post.UserID))
+
+	return nil
+}
+
+func (s *Stats) PostDeleted(ctx context.Context, post models.Posts) error {
+	const op = "service.stats.PostDeleted"
+
+	if err := s.storage.RefreshUserStats(ctx, post.UserID); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	s.log.Info("user stats refreshed", slog.Int("userID", post.UserID))
+
+	return nil
+}
+
+func (s *Stats) Followed(ctx context.Context, req models.FollowRequest) error {
+	const op = "service.stats.Followed"
+
+	if err := s.refreshFollow(ctx, req); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+func (s *Stats) Unfollowed(ctx context.Context, req models.FollowRequest) error {
+	const op = "service.stats.Unfollowed"
+
+	if err := s.refreshFollow(ctx, req); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+// UserDeleted recounts the accounts the deleted user followed or was followed by.
+// The stats row of the deleted user goes away with ON DELETE CASCADE.
+func (s *Stats) UserDeleted(ctx context.Context, event models.UserDeletedEvent) error {
+	const op = "service.stats.UserDeleted"
+
+	for _, userIDs := range [][]int{event.Followers, event.Following} {
+		for _, userID := range userIDs {
+			if err := s.storage.RefreshUserStats(ctx, userID); err != nil {
+				return fmt.Errorf("%s: %w", op, err)
+			}
+		}
+	}
+
+	s.log.Info(
+		"user stats refreshed after user deletion",
+		slog.Int("userID", event.ID),
+		slog.Int("followers", len(event.Followers)),
+		slog.Int("following", len(event.Following)),
+	)
+
+	return nil
+}
+
+func (s *Stats) refreshFollow(ctx context.Context, req models.FollowRequest) error {
+	for _, userID := range []int{req.FollowerID, req.FollowingID} {
+		if err := s.storage.RefreshUserStats(ctx, userID); err != nil {
+			return err
+		}
+	}
+
+	s.log.Info(
+		"user stats refreshed",
+		slog.Int("followerID", req.FollowerID),
+		slog.Int("followingID", req.FollowingID),
+	)
+
+	return nil
+}
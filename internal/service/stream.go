@@ -0,0 +1,89 @@
+package service
+
+import (
+	"kirkagram/internal/models"
+	"log/slog"
+	"sync"
+)
+
+// NotificationStream wakes the open notification streams of a user when their notifications change.
+// A wake carries no data: the stream reads the changes from the storage by seq, so wakes of a slow
+// stream are coalesced instead of queued.
+type NotificationStream struct {
+	mu          sync.Mutex
+	subscribers map[int]map[chan struct{}]struct{}
+	closed      bool
+	log         *slog.Logger
+}
+
+func NewNotificationStream(log *slog.Logger) *NotificationStream {
+	return &NotificationStream{
+		subscribers: make(map[int]map[chan struct{}]struct{}),
+		log:         log,
+	}
+}
+
+// Subscribe returns the wake channel of a new stream of userID and the function that closes it.
+// The channel is closed when the hub shuts down.
+func (s *NotificationStream) Subscribe(userID int) (<-chan struct{}, func()) {
+	wake := make(chan struct{}, 1)
+
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		close(wake)
+
+		return wake, func() {}
+	}
+	if s.subscribers[userID] == nil {
+		s.subscribers[userID] = make(map[chan struct{}]struct{})
+	}
+	s.subscribers[userID][wake] = struct{}{}
+	s.mu.Unlock()
+
+	unsubscribe := func() {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+
+		delete(s.subscribers[userID], wake)
+		if len(s.subscribers[userID]) == 0 {
+			delete(s.subscribers, userID)
+		}
+	}
+
+	return wake, unsubscribe
+}
+
+// NotificationUpdated wakes every open stream of the user of the event.
+func (s *NotificationStream) NotificationUpdated(event models.NotificationUpdatedEvent) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for wake := range s.subscribers[event.UserID] {
+		select {
+		case wake <- struct{}{}:
+		default:
+			// The stream has a pending wake and will read this change too.
+		}
+	}
+
+	return nil
+}
+
+// Close ends every open stream, so the server shutdown doesn't wait for them.
+func (s *NotificationStream) Close() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.closed = true
+
+	for _, wakes := range s.subscribers {
+		for wake := range wakes {
+			close(wake)
+		}
+	}
+
+	s.subscribers = make(map[int]map[chan struct{}]struct{})
+
+	s.log.Info("notification streams closed")
+}
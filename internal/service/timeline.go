@@ -0,0 +1,80 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"kirkagram/internal/models"
+	"log/slog"
+)
+
+// feedBackfillLimit is how many of the latest posts of a followed account land in the feed right after a follow.
+const feedBackfillLimit = 100
+
+type TimelineService interface {
+	AddPostToFollowers(ctx context.Context, post models.Posts) (int64, error)
+	Backfill(ctx context.Context, followerID int, followingID int, limit int) (int64, error)
+	RemoveAuthor(ctx context.Context, followerID int, followingID int) (int64, error)
+}
+
+// Timeline keeps the materialized home feeds in sync with posts and follows.
+// Posts removed from the post table leave the feeds through ON DELETE CASCADE.
+type Timeline struct {
+	storage TimelineService
+	log     *slog.Logger
+}
+
+func NewTimelineService(storage TimelineService, log *slog.Logger) *Timeline {
+	return &Timeline{
+		storage: storage,
+		log:     log,
+	}
+}
+
+func (t *Timeline) PostCreated(ctx context.Context, post models.Posts) error {
+	const op = "service.timeline.PostCreated"
+
+	num, err := t.storage.AddPostToFollowers(ctx, post)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	t.log.Info("post fanned out", slog.Int("postID", post.ID), slog.Int64("timelines", num))
+
+	return nil
+}
+
+func (t *Timeline) Followed(ctx context.Context, req models.FollowRequest) error {
+	const op = "service.timeline.Followed"
+
+	num, err := t.storage.Backfill(ctx, req.FollowerID, req.FollowingID, feedBackfillLimit)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	t.log.Info(
+		"feed backfilled",
+		slog.Int("followerID", req.FollowerID),
+		slog.Int("followingID", req.FollowingID),
+		slog.Int64("posts", num),
+	)
+
+	return nil
+}
+
+func (t *Timeline) Unfollowed(ctx context.Context, req models.FollowRequest) error {
+	const op = "service.timeline.Unfollowed"
+
+	num, err := t.storage.RemoveAuthor(ctx, req.FollowerID, req.FollowingID)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	t.log.Info(
+		"author removed from feed",
+		slog.Int("followerID", req.FollowerID),
+		slog.Int("followingID", req.FollowingID),
+		slog.Int64("posts", num),
+	)
+
+	return nil
+}
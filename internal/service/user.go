@@ -5,6 +5,7 @@ import (
 	"errors"
 	"fmt"
 	"golang.org/x/crypto/bcrypt"
+	"kirkagram/internal/lib/pagination"
 	"kirkagram/internal/models"
 	"kirkagram/internal/storage"
 	"log/slog"
@@ -17,13 +18,13 @@ const (
 )
 
 type UserService interface {
-	GetByID(ID string) (*models.GetUserResponse, error)
-	Update(updateUser models.UpdateUserRequest) error
-	GetAllFollowers(userID int) (*[]models.GetAllFollowersResponse, error)
-	GetAllFollowing(userID int) (*[]models.GetAllFollowersResponse, error)
-	UploadProfilePic(userID int, filename string) error
-	DeleteUser(ID int64) error
-	CreateUser(user *models.CreateUserRequest) error
+	GetByID(ctx context.Context, ID string) (*models.GetUserResponse, error)
+	Update(ctx context.Context, updateUser models.UpdateUserRequest) error
+	GetAllFollowers(ctx context.Context, userID int, after *models.Cursor, limit int) ([]models.GetAllFollowersResponse, *models.Cursor, error)
+	GetAllFollowing(ctx context.Context, userID int, after *models.Cursor, limit int) ([]models.GetAllFollowersResponse, *models.Cursor, error)
+	UploadProfilePic(ctx context.Context, userID int, filename string) error
+	DeleteUser(ctx context.Context, ID int64) error
+	CreateUser(ctx context.Context, user *models.CreateUserRequest) error
 }
 
 type User struct {
@@ -35,7 +36,7 @@ func NewUserService(log *slog.Logger, storage UserService) *User {
 	return &User{storage: storage, log: log}
 }
 
-func (s *User) RegisterUser(user models.CreateUserRequest) error {
+func (s *User) RegisterUser(ctx context.Context, user models.CreateUserRequest) error {
 	const op = "service.User.RegisterUser"
 
 	err := validator.New().Struct(user)
@@ -54,26 +55,47 @@ func (s *User) RegisterUser(user models.CreateUserRequest) error {
 		Email:    user.Email,
 	}
 
-	return s.storage.CreateUser(&newUser)
+	return s.storage.CreateUser(ctx, &newUser)
 }
 
-func (s *User) DeleteUser(ID int64) error {
-	return s.storage.DeleteUser(ID)
+func (s *User) DeleteUser(ctx context.Context, callerID int, ID int64) error {
+	const op = "service.user.DeleteUser"
+
+	if err := authorize(callerID, int(ID)); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return s.storage.DeleteUser(ctx, ID)
 }
 
-func (s *User) UploadProfilePic(userID int, filename string) error {
-	return s.storage.UploadProfilePic(userID, filename)
+func (s *User) UploadProfilePic(ctx context.Context, userID int, filename string) error {
+	return s.storage.UploadProfilePic(ctx, userID, filename)
 }
 
 func (s *User) GetByID(ctx context.Context, ID string) (*models.GetUserResponse, error) {
 	const op = "service.user.GetByEmail"
 
-	return s.storage.GetByID(ID)
+	user, err := s.storage.GetByID(ctx, ID)
+	if err != nil {
+		return nil, err
+	}
+
+	user.ProfilePicURLs = photoURLs(user.ProfilePic)
+
+	return user, nil
 }
 
-func (s *User) Update(ctx context.Context, updateUser models.UpdateUserRequest) error {
+func (s *User) Update(ctx context.Context, callerID int, updateUser models.UpdateUserRequest) error {
 	const op = "service.user.Update"
 
+	if updateUser.ID == 0 {
+		updateUser.ID = callerID
+	}
+
+	if err := authorize(callerID, updateUser.ID); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
 	validate := validator.New()
 	emailStr := models.GetUserValidate{Email: updateUser.Email}
 
@@ -83,10 +105,10 @@ func (s *User) Update(ctx context.Context, updateUser models.UpdateUserRequest)
 			s.log.Error(fmt.Sprintf("Field: %s, Tag: %s\n", err.Field(), err.Tag()))
 		}
 
-		return fmt.Errorf("%s: %w", op, models.ErrEmailValidate)
+		return fmt.Errorf("%s: %s", op, models.ErrEmailValidate)
 	}
 
-	err = s.storage.Update(updateUser)
+	err = s.storage.Update(ctx, updateUser)
 	if err != nil {
 		if errors.Is(err, storage.ErrUserNotFound) {
 			s.log.Error("Get user by email with error", slog.String("email", updateUser.Email), slog.String("error", err.Error()))
@@ -100,34 +122,48 @@ func (s *User) Update(ctx context.Context, updateUser models.UpdateUserRequest)
 	return nil
 }
 
-func (s *User) GetAllFollowers(ctx context.Context, userID int) (*[]models.GetAllFollowersResponse, error) {
+func (s *User) GetAllFollowers(ctx context.Context, userID int, cursor string, limit int) (*models.Page[models.GetAllFollowersResponse], error) {
 	const op = "service.user.GetAllFollowers"
 
-	followers, err := s.storage.GetAllFollowers(userID)
+	after, err := pagination.DecodeCursor(cursor)
 	if err != nil {
-		if errors.Is(err, storage.ErrUserNotFound) {
-			s.log.Error("Get followers by userID with error", slog.Int("userID", userID), slog.String("error", err.Error()))
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
 
-			return nil, fmt.Errorf("%s: %w", op, storage.ErrUserNotFound)
-		}
+	followers, next, err := s.storage.GetAllFollowers(ctx, userID, after, pagination.Limit(limit))
+	if err != nil {
+		s.log.Error("Get followers by userID with error", slog.Int("userID", userID), slog.String("error", err.Error()))
+
+		return nil, fmt.Errorf("%s: %w", op, err)
 	}
 
-	return followers, nil
+	for i := range followers {
+		followers[i].ProfilePicURLs = photoURLs(followers[i].ProfilePic)
+	}
+
+	return pagination.NewPage(followers, next), nil
 }
 
-func (s *User) GetAllFollowing(ctx context.Context, userID int) (*[]models.GetAllFollowersResponse, error) {
+func (s *User) GetAllFollowing(ctx context.Context, userID int, cursor string, limit int) (*models.Page[models.GetAllFollowersResponse], error) {
 	const op = "service.user.GetAllFollowing"
 
-	following, err := s.storage.GetAllFollowing(userID)
+	after, err := pagination.DecodeCursor(cursor)
 	if err != nil {
-		if errors.Is(err, storage.ErrUserNotFound) {
-			s.log.Error("Get followers by userID with error", slog.Int("userID", userID), slog.String("error", err.Error()))
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
 
-			return nil, fmt.Errorf("%s: %w", op, storage.ErrUserNotFound)
-		}
+	following, next, err := s.storage.GetAllFollowing(ctx, userID, after, pagination.Limit(limit))
+	if err != nil {
+		s.log.Error("Get following by userID with error", slog.Int("userID", userID), slog.String("error", err.Error()))
+
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	for i := range following {
+		following[i].ProfilePicURLs = photoURLs(following[i].ProfilePic)
 	}
 
-	return following, nil
+	return pagination.NewPage(following, next), nil
 }
 
 func hashPassword(password string) (string, error) {
@@ -0,0 +1,104 @@
+package disk
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"kirkagram/internal/storage"
+	"os"
+	"path/filepath"
+)
+
+// PhotoStorage keeps every photo in a file named by its key.
+type PhotoStorage struct {
+	dir string
+}
+
+func NewPhotoStorage(dir string) *PhotoStorage {
+	return &PhotoStorage{dir: dir}
+}
+
+func (p *PhotoStorage) GetPhoto(ctx context.Context, key string) (io.ReadCloser, int64, error) {
+	const op = "storage.disk.GetPhoto"
+
+	path, err := p.path(key)
+	if err != nil {
+		return nil, 0, fmt.Errorf("%s: %w", op, storage.ErrNoSuchKey)
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil, 0, fmt.Errorf("%s: %w", op, storage.ErrNoSuchKey)
+		}
+
+		return nil, 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+
+		return nil, 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return file, info.Size(), nil
+}
+
+// UploadPhoto writes the photo to a temporary file and renames it, so a reader never sees a partial photo.
+func (p *PhotoStorage) UploadPhoto(ctx context.Context, key string, body io.Reader, size int64) error {
+	const op = "storage.disk.UploadPhoto"
+
+	path, err := p.path(key)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := os.MkdirAll(p.dir, 0o755); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	tmp, err := os.CreateTemp(p.dir, ".upload-*")
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	defer os.Remove(tmp.Name())
+
+	written, err := io.Copy(tmp, body)
+	if err != nil {
+		tmp.Close()
+
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if size >= 0 && written != size {
+		tmp.Close()
+
+		return fmt.Errorf("%s: wrote %d of %d bytes", op, written, size)
+	}
+
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := os.Chmod(tmp.Name(), 0o644); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+// path rejects keys that would leave the directory, the key of GetPhoto comes from the URL.
+func (p *PhotoStorage) path(key string) (string, error) {
+	if key == "" || key[0] == '.' || filepath.Base(key) != key {
+		return "", storage.ErrInvalidKey
+	}
+
+	return filepath.Join(p.dir, key), nil
+}
@@ -0,0 +1,55 @@
+package memory
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"kirkagram/internal/storage"
+	"sync"
+)
+
+// PhotoStorage keeps the photos in process memory, they are lost on restart.
+// A stored photo is never modified, an upload replaces the whole slice.
+type PhotoStorage struct {
+	mu     sync.RWMutex
+	photos map[string][]byte
+}
+
+func NewPhotoStorage() *PhotoStorage {
+	return &PhotoStorage{photos: make(map[string][]byte)}
+}
+
+func (p *PhotoStorage) GetPhoto(ctx context.Context, key string) (io.ReadCloser, int64, error) {
+	const op = "storage.memory.GetPhoto"
+
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	data, ok := p.photos[key]
+	if !ok {
+		return nil, 0, fmt.Errorf("%s: %w", op, storage.ErrNoSuchKey)
+	}
+
+	return io.NopCloser(bytes.NewReader(data)), int64(len(data)), nil
+}
+
+func (p *PhotoStorage) UploadPhoto(ctx context.Context, key string, body io.Reader, size int64) error {
+	const op = "storage.memory.UploadPhoto"
+
+	var buf bytes.Buffer
+	if size > 0 {
+		buf.Grow(int(size))
+	}
+
+	if _, err := buf.ReadFrom(body); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.photos[key] = buf.Bytes()
+
+	return nil
+}
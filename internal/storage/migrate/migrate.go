@@ -0,0 +1,416 @@
+package migrate
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// lockID is the key of the advisory lock that keeps concurrent runners from applying a migration twice.
+const lockID = 7_265_003
+
+var (
+	ErrSchemaOutdated   = errors.New("database schema is out of date")
+	ErrUnknownMigration = errors.New("unknown migration")
+)
+
+// fileName matches <version>_<name>.up.sql and <version>_<name>.down.sql.
+var fileName = regexp.MustCompile(`^(\d+)_(\w+)\.(up|down)\.sql$`)
+
+type Migration struct {
+	Version int
+	Name    string
+	Up      string
+	Down    string
+}
+
+// Status describes a migration known to the binary or recorded in the database. A migration recorded
+// by a newer binary has an empty Name.
+type Status struct {
+	Version   int
+	Name      string
+	AppliedAt *time.Time
+}
+
+// Migrator applies the migrations and records the applied versions in the schema_migrations table.
+// Every migration runs in its own transaction.
+type Migrator struct {
+	db         *sql.DB
+	migrations []Migration
+	log        *slog.Logger
+}
+
+func New(db *sql.DB, fsys fs.FS, log *slog.Logger) (*Migrator, error) {
+	const op = "storage.migrate.New"
+
+	migrations, err := load(fsys)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return &Migrator{
+		db:         db,
+		migrations: migrations,
+		log:        log,
+	}, nil
+}
+
+// Up applies the pending migrations in version order and returns how many were applied.
+func (m *Migrator) Up(ctx context.Context) (int, error) {
+	const op = "storage.migrate.Up"
+
+	num := 0
+
+	err := m.withLock(ctx, func(conn *sql.Conn) error {
+		applied, err := appliedVersions(ctx, conn)
+		if err != nil {
+			return err
+		}
+
+		for _, migration := range m.migrations {
+			if _, ok := applied[migration.Version]; ok {
+				continue
+			}
+
+			err := inTx(ctx, conn, migration.Up,
+				`INSERT INTO schema_migrations (version, name) VALUES ($1, $2)`,
+				migration.Version,
+				migration.Name,
+			)
+			if err != nil {
+				return fmt.Errorf("migration %d_%s: %w", migration.Version, migration.Name, err)
+			}
+
+			m.log.Info("Migration applied", slog.Int("version", migration.Version), slog.String("name", migration.Name))
+
+			num++
+		}
+
+		return nil
+	})
+	if err != nil {
+		return num, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return num, nil
+}
+
+// Down reverts the last n applied migrations, the newest first, and returns how many were reverted.
+func (m *Migrator) Down(ctx context.Context, n int) (int, error) {
+	const op = "storage.migrate.Down"
+
+	num := 0
+
+	err := m.withLock(ctx, func(conn *sql.Conn) error {
+		applied, err := appliedVersions(ctx, conn)
+		if err != nil {
+			return err
+		}
+
+		versions := make([]int, 0, len(applied))
+		for version := range applied {
+			versions = append(versions, version)
+		}
+		slices.Sort(versions)
+		slices.Reverse(versions)
+
+		for _, version := range versions[:min(n, len(versions))] {
+			migration, ok := m.find(version)
+			if !ok {
+				return fmt.Errorf("version %d: %w", version, ErrUnknownMigration)
+			}
+
+			err := inTx(ctx, conn, migration.Down, `DELETE FROM schema_migrations WHERE version = $1`, migration.Version)
+			if err != nil {
+				return fmt.Errorf("migration %d_%s: %w", migration.Version, migration.Name, err)
+			}
+
+			m.log.Info("Migration reverted", slog.Int("version", migration.Version), slog.String("name", migration.Name))
+
+			num++
+		}
+
+		return nil
+	})
+	if err != nil {
+		return num, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return num, nil
+}
+
+// Baseline records the migrations up to version as applied without running them. It is meant for
+// databases whose schema was created by applying the SQL files by hand.
+func (m *Migrator) Baseline(ctx context.Context, version int) (int, error) {
+	const op = "storage.migrate.Baseline"
+
+	if _, ok := m.find(version); !ok {
+		return 0, fmt.Errorf("%s: version %d: %w", op, version, ErrUnknownMigration)
+	}
+
+	num := 0
+
+	err := m.withLock(ctx, func(conn *sql.Conn) error {
+		for _, migration := range m.migrations {
+			if migration.Version > version {
+				break
+			}
+
+			res, err := conn.ExecContext(ctx,
+				`INSERT INTO schema_migrations (version, name) VALUES ($1, $2) ON CONFLICT DO NOTHING`,
+				migration.Version,
+				migration.Name,
+			)
+			if err != nil {
+				return err
+			}
+
+			if rows, _ := res.RowsAffected(); rows > 0 {
+				num++
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		return num, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return num, nil
+}
+
+// Status lists the migrations of the binary and the versions recorded in the database, in version order.
+func (m *Migrator) Status(ctx context.Context) ([]Status, error) {
+	const op = "storage.migrate.Status"
+
+	applied, err := m.applied(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	statuses := make([]Status, 0, len(m.migrations))
+
+	for _, migration := range m.migrations {
+		status := Status{Version: migration.Version, Name: migration.Name}
+		if appliedAt, ok := applied[migration.Version]; ok {
+			status.AppliedAt = &appliedAt
+		}
+
+		statuses = append(statuses, status)
+	}
+
+	for version, appliedAt := range applied {
+		if _, ok := m.find(version); !ok {
+			statuses = append(statuses, Status{Version: version, AppliedAt: &appliedAt})
+		}
+	}
+
+	slices.SortFunc(statuses, func(a, b Status) int {
+		return a.Version - b.Version
+	})
+
+	return statuses, nil
+}
+
+// Check returns ErrSchemaOutdated if a migration of the binary isn't applied yet. Versions applied by
+// a newer binary are only logged, so an older instance keeps working during a rolling update.
+func (m *Migrator) Check(ctx context.Context) error {
+	const op = "storage.migrate.Check"
+
+	statuses, err := m.Status(ctx)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	var pending []string
+
+	for _, status := range statuses {
+		switch {
+		case status.Name == "":
+			m.log.Warn("Database has a migration unknown to this binary", slog.Int("version", status.Version))
+		case status.AppliedAt == nil:
+			pending = append(pending, strconv.Itoa(status.Version)+"_"+status.Name)
+		}
+	}
+
+	if len(pending) > 0 {
+		return fmt.Errorf("%s: %w, pending migrations: %s", op, ErrSchemaOutdated, strings.Join(pending, ", "))
+	}
+
+	return nil
+}
+
+func (m *Migrator) find(version int) (Migration, bool) {
+	for _, migration := range m.migrations {
+		if migration.Version == version {
+			return migration, true
+		}
+	}
+
+	return Migration{}, false
+}
+
+// applied reads the applied versions without creating the schema_migrations table.
+func (m *Migrator) applied(ctx context.Context) (map[int]time.Time, error) {
+	var exists bool
+
+	err := m.db.QueryRowContext(ctx, `SELECT to_regclass('schema_migrations') IS NOT NULL`).Scan(&exists)
+	if err != nil {
+		return nil, err
+	}
+
+	if !exists {
+		return map[int]time.Time{}, nil
+	}
+
+	return appliedVersions(ctx, m.db)
+}
+
+// withLock runs fn on a single connection holding the advisory lock, with the schema_migrations table created.
+func (m *Migrator) withLock(ctx context.Context, fn func(conn *sql.Conn) error) error {
+	conn, err := m.db.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	if _, err := conn.ExecContext(ctx, `SELECT pg_advisory_lock($1)`, lockID); err != nil {
+		return err
+	}
+	defer func() {
+		// The lock is released even if ctx is canceled, the connection goes back to the pool.
+		if _, err := conn.ExecContext(context.Background(), `SELECT pg_advisory_unlock($1)`, lockID); err != nil {
+			m.log.Error("Unable to release migration lock", slog.String("error", err.Error()))
+		}
+	}()
+
+	_, err = conn.ExecContext(ctx, `
+		CREATE TABLE IF NOT EXISTS schema_migrations (
+			version INTEGER PRIMARY KEY,
+			name VARCHAR(255) NOT NULL,
+			applied_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+		)`)
+	if err != nil {
+		return err
+	}
+
+	return fn(conn)
+}
+
+type querier interface {
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+}
+
+func appliedVersions(ctx context.Context, q querier) (map[int]time.Time, error) {
+	rows, err := q.QueryContext(ctx, `SELECT version, applied_at FROM schema_migrations`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	applied := make(map[int]time.Time)
+
+	for rows.Next() {
+		var version int
+		var appliedAt time.Time
+
+		if err := rows.Scan(&version, &appliedAt); err != nil {
+			return nil, err
+		}
+
+		applied[version] = appliedAt
+	}
+
+	return applied, rows.Err()
+}
+
+// inTx runs the migration script and the statement recording it in one transaction.
+func inTx(ctx context.Context, conn *sql.Conn, script string, record string, args ...any) error {
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, script); err != nil {
+		return err
+	}
+
+	if _, err := tx.ExecContext(ctx, record, args...); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// load reads the migrations from fsys. Every version must have both an up and a down file.
+func load(fsys fs.FS) ([]Migration, error) {
+	entries, err := fs.ReadDir(fsys, ".")
+	if err != nil {
+		return nil, err
+	}
+
+	byVersion := make(map[int]*Migration)
+	files := make(map[string]bool)
+
+	for _, entry := range entries {
+		match := fileName.FindStringSubmatch(entry.Name())
+		if entry.IsDir() || match == nil {
+			continue
+		}
+
+		version, err := strconv.Atoi(match[1])
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", entry.Name(), err)
+		}
+
+		data, err := fs.ReadFile(fsys, entry.Name())
+		if err != nil {
+			return nil, err
+		}
+
+		migration, ok := byVersion[version]
+		if !ok {
+			migration = &Migration{Version: version, Name: match[2]}
+			byVersion[version] = migration
+		}
+
+		if migration.Name != match[2] {
+			return nil, fmt.Errorf("version %d is used by %s and %s", version, migration.Name, match[2])
+		}
+
+		if match[3] == "up" {
+			migration.Up = string(data)
+		} else {
+			migration.Down = string(data)
+		}
+
+		files[strconv.Itoa(version)+"."+match[3]] = true
+	}
+
+	migrations := make([]Migration, 0, len(byVersion))
+
+	for version, migration := range byVersion {
+		for _, direction := range []string{"up", "down"} {
+			if !files[strconv.Itoa(version)+"."+direction] {
+				return nil, fmt.Errorf("migration %d_%s has no %s file", version, migration.Name, direction)
+			}
+		}
+
+		migrations = append(migrations, *migration)
+	}
+
+	slices.SortFunc(migrations, func(a, b Migration) int {
+		return a.Version - b.Version
+	})
+
+	return migrations, nil
+}
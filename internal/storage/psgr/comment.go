@@ -0,0 +1,328 @@
+package psgr
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"github.com/lib/pq"
+	k "kirkagram/internal/kafka"
+	"kirkagram/internal/lib/pagination"
+	"kirkagram/internal/models"
+	"kirkagram/internal/storage"
+	"strconv"
+)
+
+// commentColumns selects a comment from the table aliased as c together with the number of its live replies.
+const commentColumns = `
+	c.id, c.post_id, c.user_id, c.parent_id, c.content,
+	(SELECT COUNT(*) FROM "comment" r WHERE r.parent_id = c.id AND r.deleted_at IS NULL),
+	c.deleted_at IS NOT NULL, c.created_at, c.updated_at`
+
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+type CommentStorage struct {
+	db *sql.DB
+}
+
+func NewCommentStorage(db *sql.DB) *CommentStorage {
+	return &CommentStorage{db: db}
+}
+
+func (c *CommentStorage) CreateComment(ctx context.Context, req models.CreateCommentRequest) (*models.Comments, error) {
+	const op = "storage.psgr.comment.CreateComment"
+
+	tx, err := c.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	defer tx.Rollback()
+
+	var ID int
+
+	err = tx.QueryRowContext(ctx,
+		`
+		INSERT INTO "comment" (user_id, post_id, parent_id, content)
+		VALUES ($1, $2, $3, $4)
+		RETURNING id`,
+		req.UserID,
+		req.PostID,
+		req.ParentID,
+		req.Content,
+	).Scan(&ID)
+	if err != nil {
+		var pqErr *pq.Error
+		if errors.As(err, &pqErr) {
+			switch pqErr.Message {
+			case "insert or update on table \"comment\" violates foreign key constraint \"comment_post_id_fkey\"":
+				return nil, fmt.Errorf("%s: %w", op, storage.ErrPostNotFound)
+			case "insert or update on table \"comment\" violates foreign key constraint \"comment_user_id_fkey\"":
+				return nil, fmt.Errorf("%s: %w", op, storage.ErrUserNotFound)
+			case "insert or update on table \"comment\" violates foreign key constraint \"comment_parent_id_fkey\"":
+				return nil, fmt.Errorf("%s: %w", op, storage.ErrCommentNotFound)
+			}
+		}
+
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	comment, err := scanComment(tx.QueryRowContext(ctx,
+		`SELECT `+commentColumns+` FROM "comment" c WHERE c.id = $1`,
+		ID,
+	))
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := enqueue(ctx, tx, k.CommentCreated, comment.UserID, strconv.Itoa(comment.PostID), comment); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return comment, nil
+}
+
+func (c *CommentStorage) GetCommentByID(ctx context.Context, ID int) (*models.Comments, error) {
+	const op = "storage.psgr.comment.GetCommentByID"
+
+	comment, err := scanComment(c.db.QueryRowContext(ctx,
+		`SELECT `+commentColumns+` FROM "comment" c WHERE c.id = $1`,
+		ID,
+	))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, fmt.Errorf("%s: %w", op, storage.ErrCommentNotFound)
+		}
+
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return comment, nil
+}
+
+// GetCommentsByPostID returns top-level comments of the post, newest first.
+// Deleted comments are kept as tombstones while they still have replies.
+func (c *CommentStorage) GetCommentsByPostID(ctx context.Context, postID int, after *models.Cursor, limit int) ([]models.Comments, *models.Cursor, error) {
+	const op = "storage.psgr.comment.GetCommentsByPostID"
+
+	query := `
+		SELECT ` + commentColumns + `
+		FROM "comment" c
+		WHERE c.post_id = $1
+		  AND c.parent_id IS NULL
+		  AND (c.deleted_at IS NULL OR EXISTS (SELECT 1 FROM "comment" r WHERE r.parent_id = c.id))`
+
+	var rows *sql.Rows
+	var err error
+
+	if after == nil {
+		rows, err = c.db.QueryContext(ctx, query+` ORDER BY c.created_at DESC, c.id DESC LIMIT $2`, postID, limit+1)
+	} else {
+		rows, err = c.db.QueryContext(ctx,
+			query+` AND (c.created_at, c.id) < ($3, $4) ORDER BY c.created_at DESC, c.id DESC LIMIT $2`,
+			postID,
+			limit+1,
+			after.CreatedAt,
+			after.ID,
+		)
+	}
+	if err != nil {
+		return nil, nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	comments, err := scanComments(rows)
+	if err != nil {
+		return nil, nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	comments, next := pagination.TrimPage(comments, limit, commentCursor)
+
+	return comments, next, nil
+}
+
+// GetRepliesByCommentID returns replies to the comment in the order they were written.
+func (c *CommentStorage) GetRepliesByCommentID(ctx context.Context, parentID int, after *models.Cursor, limit int) ([]models.Comments, *models.Cursor, error) {
+	const op = "storage.psgr.comment.GetRepliesByCommentID"
+
+	query := `
+		SELECT ` + commentColumns + `
+		FROM "comment" c
+		WHERE c.parent_id = $1`
+
+	var rows *sql.Rows
+	var err error
+
+	if after == nil {
+		rows, err = c.db.QueryContext(ctx, query+` ORDER BY c.created_at, c.id LIMIT $2`, parentID, limit+1)
+	} else {
+		rows, err = c.db.QueryContext(ctx,
+			query+` AND (c.created_at, c.id) > ($3, $4) ORDER BY c.created_at, c.id LIMIT $2`,
+			parentID,
+			limit+1,
+			after.CreatedAt,
+			after.ID,
+		)
+	}
+	if err != nil {
+		return nil, nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	comments, err := scanComments(rows)
+	if err != nil {
+		return nil, nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	comments, next := pagination.TrimPage(comments, limit, commentCursor)
+
+	return comments, next, nil
+}
+
+func (c *CommentStorage) UpdateComment(ctx context.Context, ID int, content string) error {
+	const op = "storage.psgr.comment.UpdateComment"
+
+	exec, err := c.db.ExecContext(ctx,
+		`UPDATE "comment" SET content = $1, updated_at = CURRENT_TIMESTAMP WHERE id = $2 AND deleted_at IS NULL`,
+		content,
+		ID,
+	)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	num, err := exec.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if num == 0 {
+		return fmt.Errorf("%s: %w", op, storage.ErrCommentNotFound)
+	}
+
+	return nil
+}
+
+// DeleteComment removes the comment. A comment that has replies is turned into a tombstone instead,
+// and a tombstone is removed together with its last reply.
+func (c *CommentStorage) DeleteComment(ctx context.Context, ID int) error {
+	const op = "storage.psgr.comment.DeleteComment"
+
+	tx, err := c.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	defer tx.Rollback()
+
+	var parentID sql.NullInt64
+
+	// Row lock conflicts with the key share lock taken by inserts of new replies.
+	err = tx.QueryRowContext(ctx,
+		`SELECT parent_id FROM "comment" WHERE id = $1 AND deleted_at IS NULL FOR UPDATE`,
+		ID,
+	).Scan(&parentID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return fmt.Errorf("%s: %w", op, storage.ErrCommentNotFound)
+		}
+
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	var hasReplies bool
+
+	err = tx.QueryRowContext(ctx,
+		`SELECT EXISTS (SELECT 1 FROM "comment" WHERE parent_id = $1)`,
+		ID,
+	).Scan(&hasReplies)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if hasReplies {
+		_, err = tx.ExecContext(ctx,
+			`UPDATE "comment" SET content = '', deleted_at = CURRENT_TIMESTAMP WHERE id = $1`,
+			ID,
+		)
+	} else {
+		_, err = tx.ExecContext(ctx, `DELETE FROM "comment" WHERE id = $1`, ID)
+	}
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if parentID.Valid {
+		_, err = tx.ExecContext(ctx,
+			`
+			DELETE FROM "comment" c
+			WHERE c.id = $1
+			  AND c.deleted_at IS NOT NULL
+			  AND NOT EXISTS (SELECT 1 FROM "comment" r WHERE r.parent_id = c.id)`,
+			parentID.Int64,
+		)
+		if err != nil {
+			return fmt.Errorf("%s: %w", op, err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+func scanComment(row rowScanner) (*models.Comments, error) {
+	var comment models.Comments
+	var parentID sql.NullInt64
+
+	err := row.Scan(
+		&comment.ID,
+		&comment.PostID,
+		&comment.UserID,
+		&parentID,
+		&comment.Content,
+		&comment.ReplyCount,
+		&comment.Deleted,
+		&comment.CreatedAt,
+		&comment.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	if parentID.Valid {
+		ID := int(parentID.Int64)
+		comment.ParentID = &ID
+	}
+
+	return &comment, nil
+}
+
+func scanComments(rows *sql.Rows) ([]models.Comments, error) {
+	defer rows.Close()
+
+	comments := []models.Comments{}
+
+	for rows.Next() {
+		comment, err := scanComment(rows)
+		if err != nil {
+			return nil, err
+		}
+
+		comments = append(comments, *comment)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return comments, nil
+}
+
+func commentCursor(comment models.Comments) models.Cursor {
+	return models.Cursor{CreatedAt: comment.CreatedAt, ID: comment.ID}
+}
@@ -0,0 +1,95 @@
+package psgr
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"kirkagram/internal/lib/pagination"
+	"kirkagram/internal/models"
+)
+
+type FeedStorage struct {
+	db *sql.DB
+}
+
+func NewFeedStorage(db *sql.DB) *FeedStorage {
+	return &FeedStorage{db: db}
+}
+
+// GetFeed returns posts of the accounts userID follows, newest first, starting after the cursor.
+func (f *FeedStorage) GetFeed(ctx context.Context, userID int, after *models.Cursor, limit int) ([]models.FeedItem, *models.Cursor, error) {
+	const op = "storage.psgr.feed.GetFeed"
+
+	var rows *sql.Rows
+	var err error
+
+	if after == nil {
+		rows, err = f.db.QueryContext(ctx, feedQuery(""), userID, limit+1)
+	} else {
+		rows, err = f.db.QueryContext(ctx,
+			feedQuery(`AND (fe.created_at, fe.post_id) < ($3, $4)`),
+			userID,
+			limit+1,
+			after.CreatedAt,
+			after.ID,
+		)
+	}
+	if err != nil {
+		return nil, nil, fmt.Errorf("%s: %w", op, err)
+	}
+	defer rows.Close()
+
+	items := []models.FeedItem{}
+
+	for rows.Next() {
+		var item models.FeedItem
+
+		err := rows.Scan(
+			&item.ID,
+			&item.UserID,
+			&item.Username,
+			&item.ProfilePic,
+			&item.ImageURL,
+			&item.Caption,
+			&item.LikeCount,
+			&item.LikedByMe,
+			&item.CreatedAt,
+		)
+		if err != nil {
+			return nil, nil, fmt.Errorf("%s: %w", op, err)
+		}
+
+		items = append(items, item)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	items, next := pagination.TrimPage(items, limit, func(item models.FeedItem) models.Cursor {
+		return models.Cursor{CreatedAt: item.CreatedAt, ID: item.ID}
+	})
+
+	return items, next, nil
+}
+
+// feedQuery reads the materialized timeline filled by the feed worker.
+func feedQuery(cursorCondition string) string {
+	return `
+		SELECT
+			p.id,
+			p.user_id,
+			u.username,
+			COALESCE(u.profile_pic, ''),
+			p.image_url,
+			COALESCE(p.caption, ''),
+			(SELECT COUNT(*) FROM "like" l WHERE l.post_id = p.id),
+			EXISTS (SELECT 1 FROM "like" l WHERE l.post_id = p.id AND l.user_id = $1),
+			fe.created_at
+		FROM "feed_entry" fe
+		JOIN "post" p ON p.id = fe.post_id
+		JOIN "users" u ON u.id = fe.author_id
+		WHERE fe.user_id = $1 ` + cursorCondition + `
+		ORDER BY fe.created_at DESC, fe.post_id DESC
+		LIMIT $2`
+}
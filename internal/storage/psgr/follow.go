@@ -1,12 +1,15 @@
 package psgr
 
 import (
+	"context"
 	"database/sql"
 	"errors"
 	"fmt"
 	"github.com/lib/pq"
+	k "kirkagram/internal/kafka"
 	"kirkagram/internal/models"
 	"kirkagram/internal/storage"
+	"strconv"
 )
 
 type FollowStorage struct {
@@ -17,14 +20,20 @@ func NewFollowStorage(db *sql.DB) *FollowStorage {
 	return &FollowStorage{db: db}
 }
 
-func (f *FollowStorage) FollowByID(req models.FollowRequest) error {
+func (f *FollowStorage) FollowByID(ctx context.Context, req models.FollowRequest) error {
 	const op = "storage.psgr.follow.FollowByID"
 
 	if req.FollowerID == req.FollowingID {
 		return fmt.Errorf("%s: %w", op, storage.SelfFollowError)
 	}
 
-	_, err := f.db.Exec(`
+	tx, err := f.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	defer tx.Rollback()
+
+	_, err = tx.ExecContext(ctx, `
 		INSERT INTO "follow" ("follower_id", "following_id")
 		VALUES ($1, $2)
 		`,
@@ -46,17 +55,31 @@ func (f *FollowStorage) FollowByID(req models.FollowRequest) error {
 		return fmt.Errorf("%s: %w", op, err)
 	}
 
+	if err := enqueue(ctx, tx, k.FollowCreated, req.FollowerID, strconv.Itoa(req.FollowerID), req); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
 	return nil
 }
 
-func (f *FollowStorage) UnFollowByID(req models.FollowRequest) error {
+func (f *FollowStorage) UnFollowByID(ctx context.Context, req models.FollowRequest) error {
 	const op = "storage.psgr.follow.UnFollow"
 
 	if req.FollowerID == req.FollowingID {
 		return fmt.Errorf("%s: %w", op, storage.SelfUnFollowError)
 	}
 
-	_, err := f.db.Exec(
+	tx, err := f.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	defer tx.Rollback()
+
+	_, err = tx.ExecContext(ctx,
 		`DELETE FROM "follow" WHERE follower_id = $1 AND following_id = $2`,
 		req.FollowerID,
 		req.FollowingID,
@@ -76,5 +99,13 @@ func (f *FollowStorage) UnFollowByID(req models.FollowRequest) error {
 		return fmt.Errorf("%s: %w", op, err)
 	}
 
+	if err := enqueue(ctx, tx, k.FollowDeleted, req.FollowerID, strconv.Itoa(req.FollowerID), req); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
 	return nil
 }
@@ -1,12 +1,15 @@
 package psgr
 
 import (
+	"context"
 	"database/sql"
 	"errors"
 	"fmt"
 	"github.com/lib/pq"
+	k "kirkagram/internal/kafka"
 	"kirkagram/internal/models"
 	"kirkagram/internal/storage"
+	"strconv"
 )
 
 type LikeStorage struct {
@@ -17,12 +20,12 @@ func NewLikeStorage(db *sql.DB) *LikeStorage {
 	return &LikeStorage{db: db}
 }
 
-func (l *LikeStorage) GetLikesByID(postID int) (models.LikeResponse, error) {
+func (l *LikeStorage) GetLikesByID(ctx context.Context, postID int) (models.LikeResponse, error) {
 	const op = "storage.psgr.like.GetLikesByID"
 
 	var count int
 
-	err := l.db.QueryRow(
+	err := l.db.QueryRowContext(ctx,
 		`SELECT COUNT(*) FROM "like" WHERE post_id = $1;`,
 		postID,
 	).Scan(&count)
@@ -37,10 +40,16 @@ func (l *LikeStorage) GetLikesByID(postID int) (models.LikeResponse, error) {
 	return models.LikeResponse{Count: count}, nil
 }
 
-func (l *LikeStorage) UnlikePostByID(likeReq *models.LikeRequest) error {
-	const op = "storage.psgr.like.LikePostByID"
+func (l *LikeStorage) UnlikePostByID(ctx context.Context, likeReq *models.LikeRequest) error {
+	const op = "storage.psgr.like.UnlikePostByID"
+
+	tx, err := l.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	defer tx.Rollback()
 
-	exec, err := l.db.Exec(
+	exec, err := tx.ExecContext(ctx,
 		`DELETE FROM "like" WHERE user_id = $1 AND post_id = $2`,
 		likeReq.UserID,
 		likeReq.PostID,
@@ -61,13 +70,27 @@ func (l *LikeStorage) UnlikePostByID(likeReq *models.LikeRequest) error {
 		return fmt.Errorf("%s: %w", op, storage.ErrLikeNotFound)
 	}
 
+	if err := enqueue(ctx, tx, k.LikeDeleted, likeReq.UserID, strconv.Itoa(likeReq.PostID), likeReq); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
 	return nil
 }
 
-func (l *LikeStorage) LikePostByID(likeReq *models.LikeRequest) error {
+func (l *LikeStorage) LikePostByID(ctx context.Context, likeReq *models.LikeRequest) error {
 	const op = "storage.psgr.like.LikePostByID"
 
-	exec, err := l.db.Exec(
+	tx, err := l.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	defer tx.Rollback()
+
+	exec, err := tx.ExecContext(ctx,
 		`INSERT INTO "like" (user_id, post_id) VALUES ($1, $2)`,
 		likeReq.UserID,
 		likeReq.PostID,
@@ -93,5 +116,13 @@ func (l *LikeStorage) LikePostByID(likeReq *models.LikeRequest) error {
 		return storage.ErrPostAlreadyLiked
 	}
 
+	if err := enqueue(ctx, tx, k.LikeCreated, likeReq.UserID, strconv.Itoa(likeReq.PostID), likeReq); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
 	return nil
 }
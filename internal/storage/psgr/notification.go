@@ -0,0 +1,442 @@
+package psgr
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"github.com/lib/pq"
+	k "kirkagram/internal/kafka"
+	"kirkagram/internal/lib/pagination"
+	"kirkagram/internal/models"
+	"strconv"
+)
+
+type NotificationStorage struct {
+	db *sql.DB
+}
+
+func NewNotificationStorage(db *sql.DB) *NotificationStorage {
+	return &NotificationStorage{db: db}
+}
+
+// AddActor adds the actor to the unread notification group of the user, the group is created on the first event.
+// Adding the same actor twice keeps one actor, so a redelivered event is not counted again.
+func (n *NotificationStorage) AddActor(ctx context.Context, userID int, notificationType string, postID *int, actorID int) error {
+	const op = "storage.psgr.notification.AddActor"
+
+	tx, err := n.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	defer tx.Rollback()
+
+	event := models.NotificationUpdatedEvent{UserID: userID}
+
+	err = tx.QueryRowContext(ctx,
+		`
+		INSERT INTO "notification" (user_id, type, post_id)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (user_id, type, COALESCE(post_id, 0)) WHERE read_at IS NULL
+		DO UPDATE SET updated_at = CURRENT_TIMESTAMP
+		RETURNING id`,
+		userID,
+		notificationType,
+		postID,
+	).Scan(&event.NotificationID)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	_, err = tx.ExecContext(ctx,
+		`
+		INSERT INTO "notification_actor" (notification_id, actor_id)
+		VALUES ($1, $2)
+		ON CONFLICT (notification_id, actor_id) DO UPDATE SET created_at = CURRENT_TIMESTAMP`,
+		event.NotificationID,
+		actorID,
+	)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if event.Seq, err = touch(ctx, tx, userID, event.NotificationID); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	// Open notification streams of the user learn about the change from this event.
+	if err := enqueue(ctx, tx, k.NotificationUpdated, actorID, strconv.Itoa(userID), event); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+// RemoveActor takes the actor out of the unread notification group, for example after an unlike.
+// A group left without actors is kept, so open streams learn that it is gone, and is hidden from the inbox.
+// Read notifications are kept as they are.
+func (n *NotificationStorage) RemoveActor(ctx context.Context, userID int, notificationType string, postID *int, actorID int) error {
+	const op = "storage.psgr.notification.RemoveActor"
+
+	tx, err := n.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	defer tx.Rollback()
+
+	event := models.NotificationUpdatedEvent{UserID: userID}
+
+	err = tx.QueryRowContext(ctx,
+		`
+		SELECT id FROM "notification"
+		WHERE user_id = $1 AND type = $2 AND COALESCE(post_id, 0) = COALESCE($3, 0) AND read_at IS NULL
+		FOR UPDATE`,
+		userID,
+		notificationType,
+		postID,
+	).Scan(&event.NotificationID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	exec, err := tx.ExecContext(ctx,
+		`DELETE FROM "notification_actor" WHERE notification_id = $1 AND actor_id = $2`,
+		event.NotificationID,
+		actorID,
+	)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	num, err := exec.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	if num == 0 {
+		return nil
+	}
+
+	if event.Seq, err = touch(ctx, tx, userID, event.NotificationID); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := enqueue(ctx, tx, k.NotificationUpdated, actorID, strconv.Itoa(userID), event); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+// GetNotifications returns the notification groups of the user, the most recently updated first.
+func (n *NotificationStorage) GetNotifications(ctx context.Context, userID int, after *models.Cursor, limit int) ([]models.Notification, *models.Cursor, error) {
+	const op = "storage.psgr.notification.GetNotifications"
+
+	var rows *sql.Rows
+	var err error
+
+	if after == nil {
+		rows, err = n.db.QueryContext(ctx,
+			notificationSelect+` WHERE n.user_id = $1 ORDER BY n.updated_at DESC, n.id DESC LIMIT $2`,
+			userID,
+			limit+1,
+		)
+	} else {
+		rows, err = n.db.QueryContext(ctx,
+			notificationSelect+`
+			WHERE n.user_id = $1 AND (n.updated_at, n.id) < ($3, $4)
+			ORDER BY n.updated_at DESC, n.id DESC
+			LIMIT $2`,
+			userID,
+			limit+1,
+			after.CreatedAt,
+			after.ID,
+		)
+	}
+	if err != nil {
+		return nil, nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	notifications, err := scanNotifications(rows)
+	if err != nil {
+		return nil, nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	notifications, next := pagination.TrimPage(notifications, limit, func(notification models.Notification) models.Cursor {
+		return models.Cursor{CreatedAt: notification.UpdatedAt, ID: notification.ID}
+	})
+
+	return notifications, next, nil
+}
+
+// GetNotificationsAfter returns the notification groups of the user changed after seq, in the order of changes.
+// Groups left without actors are returned too, with no last actor and an actor count of 0.
+func (n *NotificationStorage) GetNotificationsAfter(ctx context.Context, userID int, seq int64, limit int) ([]models.Notification, error) {
+	const op = "storage.psgr.notification.GetNotificationsAfter"
+
+	rows, err := n.db.QueryContext(ctx,
+		notificationChangesSelect+` WHERE n.user_id = $1 AND n.seq > $2 ORDER BY n.seq LIMIT $3`,
+		userID,
+		seq,
+		limit,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	notifications, err := scanNotifications(rows)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return notifications, nil
+}
+
+// GetLatestSeq returns the seq of the last change of the notifications of the user, 0 if there are none.
+func (n *NotificationStorage) GetLatestSeq(ctx context.Context, userID int) (int64, error) {
+	const op = "storage.psgr.notification.GetLatestSeq"
+
+	var seq int64
+
+	err := n.db.QueryRowContext(ctx,
+		`SELECT COALESCE((SELECT seq FROM "notification_counter" WHERE user_id = $1), 0)`,
+		userID,
+	).Scan(&seq)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return seq, nil
+}
+
+func (n *NotificationStorage) GetUnreadCount(ctx context.Context, userID int) (int, error) {
+	const op = "storage.psgr.notification.GetUnreadCount"
+
+	var count int
+
+	err := n.db.QueryRowContext(ctx,
+		`
+		SELECT COUNT(*) FROM "notification" n
+		WHERE n.user_id = $1
+		  AND n.read_at IS NULL
+		  AND EXISTS (SELECT 1 FROM "notification_actor" a WHERE a.notification_id = n.id)`,
+		userID,
+	).Scan(&count)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return count, nil
+}
+
+// MarkRead marks the listed notifications of the user read, all of them if IDs is empty.
+// Every marked group gets a new seq, so the streams open on other devices learn about it.
+func (n *NotificationStorage) MarkRead(ctx context.Context, userID int, IDs []int) (int64, error) {
+	const op = "storage.psgr.notification.MarkRead"
+
+	tx, err := n.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+	defer tx.Rollback()
+
+	query := `
+		SELECT n.id FROM "notification" n
+		WHERE n.user_id = $1
+		  AND n.read_at IS NULL
+		  AND EXISTS (SELECT 1 FROM "notification_actor" a WHERE a.notification_id = n.id)`
+	args := []any{userID}
+
+	if len(IDs) > 0 {
+		query += ` AND n.id = ANY($2)`
+		args = append(args, pq.Array(IDs))
+	}
+
+	rows, err := tx.QueryContext(ctx, query+` ORDER BY n.id FOR UPDATE`, args...)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	marked := []int{}
+
+	for rows.Next() {
+		var ID int
+		if err := rows.Scan(&ID); err != nil {
+			rows.Close()
+
+			return 0, fmt.Errorf("%s: %w", op, err)
+		}
+
+		marked = append(marked, ID)
+	}
+	rows.Close()
+
+	if err := rows.Err(); err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if len(marked) == 0 {
+		return 0, nil
+	}
+
+	last, err := nextSeq(ctx, tx, userID, len(marked))
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	seqs := make([]int64, len(marked))
+	for i := range marked {
+		seqs[i] = last - int64(len(marked)-1-i)
+	}
+
+	_, err = tx.ExecContext(ctx,
+		`
+		UPDATE "notification" n
+		SET read_at = CURRENT_TIMESTAMP, seq = m.seq
+		FROM unnest($1::integer[], $2::bigint[]) AS m(id, seq)
+		WHERE n.id = m.id`,
+		pq.Array(marked),
+		pq.Array(seqs),
+	)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	for i, ID := range marked {
+		event := models.NotificationUpdatedEvent{UserID: userID, NotificationID: ID, Seq: seqs[i]}
+
+		if err := enqueue(ctx, tx, k.NotificationUpdated, userID, strconv.Itoa(userID), event); err != nil {
+			return 0, fmt.Errorf("%s: %w", op, err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return int64(len(marked)), nil
+}
+
+// touch gives the notification group the next seq of the user.
+func touch(ctx context.Context, tx *sql.Tx, userID int, notificationID int) (int64, error) {
+	seq, err := nextSeq(ctx, tx, userID, 1)
+	if err != nil {
+		return 0, err
+	}
+
+	_, err = tx.ExecContext(ctx, `UPDATE "notification" SET seq = $1 WHERE id = $2`, seq, notificationID)
+	if err != nil {
+		return 0, err
+	}
+
+	return seq, nil
+}
+
+// nextSeq takes the next n seq values of the user and returns the last one. The counter row stays locked
+// until the transaction ends, so the changes of a user commit in the order of their seq and a stream that
+// has read a seq never finds a smaller one committed later. The groups changed are locked before the
+// counter, in the order of their IDs, so concurrent changes of a user can't deadlock.
+func nextSeq(ctx context.Context, tx *sql.Tx, userID int, n int) (int64, error) {
+	var seq int64
+
+	err := tx.QueryRowContext(ctx,
+		`
+		INSERT INTO "notification_counter" (user_id, seq)
+		VALUES ($1, $2)
+		ON CONFLICT (user_id) DO UPDATE SET seq = "notification_counter".seq + EXCLUDED.seq
+		RETURNING seq`,
+		userID,
+		n,
+	).Scan(&seq)
+	if err != nil {
+		return 0, err
+	}
+
+	return seq, nil
+}
+
+// notificationColumns are the columns scanNotifications reads, the last actor is joined as u.
+const notificationColumns = `
+	SELECT
+		n.id,
+		n.type,
+		n.post_id,
+		n.read_at IS NOT NULL,
+		n.seq,
+		n.created_at,
+		n.updated_at,
+		(SELECT COUNT(*) FROM "notification_actor" a WHERE a.notification_id = n.id),
+		COALESCE(u.id, 0),
+		COALESCE(u.username, ''),
+		COALESCE(u.profile_pic, '')
+	FROM "notification" n`
+
+// lastActorJoin selects the actor of the latest event of the group.
+const lastActorJoin = `
+	JOIN LATERAL (
+		SELECT a.actor_id FROM "notification_actor" a
+		WHERE a.notification_id = n.id
+		ORDER BY a.created_at DESC
+		LIMIT 1
+	) last ON true`
+
+// notificationSelect selects notification groups with their size and the actor of the latest event.
+// Groups whose actors were all removed or deleted are left out by the lateral join.
+const notificationSelect = notificationColumns + lastActorJoin + `
+	JOIN "users" u ON u.id = last.actor_id`
+
+// notificationChangesSelect selects notification groups like notificationSelect, groups left without
+// actors included.
+const notificationChangesSelect = notificationColumns + ` LEFT` + lastActorJoin + `
+	LEFT JOIN "users" u ON u.id = last.actor_id`
+
+func scanNotifications(rows *sql.Rows) ([]models.Notification, error) {
+	defer rows.Close()
+
+	notifications := []models.Notification{}
+
+	for rows.Next() {
+		var notification models.Notification
+		var postID sql.NullInt64
+
+		err := rows.Scan(
+			&notification.ID,
+			&notification.Type,
+			&postID,
+			&notification.Read,
+			&notification.Seq,
+			&notification.CreatedAt,
+			&notification.UpdatedAt,
+			&notification.ActorCount,
+			&notification.LastActor.ID,
+			&notification.LastActor.Username,
+			&notification.LastActor.ProfilePic,
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		if postID.Valid {
+			ID := int(postID.Int64)
+			notification.PostID = &ID
+		}
+
+		notifications = append(notifications, notification)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return notifications, nil
+}
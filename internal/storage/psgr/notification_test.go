@@ -0,0 +1,261 @@
+package psgr
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"io"
+	"kirkagram/internal/models"
+	"kirkagram/internal/storage/migrate"
+	"kirkagram/migrations"
+	"log/slog"
+	"os"
+	"testing"
+	"time"
+)
+
+// testDB connects to the Postgres database in KIRKAGRAM_TEST_POSTGRES and migrates it.
+// The test is skipped when the variable isn't set.
+func testDB(t *testing.T) *sql.DB {
+	t.Helper()
+
+	dsn := os.Getenv("KIRKAGRAM_TEST_POSTGRES")
+	if dsn == "" {
+		t.Skip("KIRKAGRAM_TEST_POSTGRES is not set")
+	}
+
+	db, err := sql.Open("postgres", dsn)
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	migrator, err := migrate.New(db, migrations.FS, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	if err != nil {
+		t.Fatalf("load migrations: %v", err)
+	}
+
+	if _, err := migrator.Up(context.Background()); err != nil {
+		t.Fatalf("migrate: %v", err)
+	}
+
+	return db
+}
+
+var testUsers int
+
+// createTestUser adds a user that is deleted with its notifications and events when the test ends.
+func createTestUser(t *testing.T, db *sql.DB) int {
+	t.Helper()
+
+	testUsers++
+	name := fmt.Sprintf("test_%d_%d", time.Now().UnixNano(), testUsers)
+
+	var ID int
+
+	err := db.QueryRow(
+		`INSERT INTO "users" (username, email, password) VALUES ($1, $2, 'x') RETURNING id`,
+		name,
+		name+"@example.com",
+	).Scan(&ID)
+	if err != nil {
+		t.Fatalf("create user: %v", err)
+	}
+
+	t.Cleanup(func() {
+		db.Exec(`DELETE FROM "users" WHERE id = $1`, ID)
+		db.Exec(`DELETE FROM "outbox" WHERE convert_from(payload, 'UTF8')::jsonb->>'key' = $1`, fmt.Sprint(ID))
+	})
+
+	return ID
+}
+
+func notificationsAfter(t *testing.T, s *NotificationStorage, userID int, seq int64) []models.Notification {
+	t.Helper()
+
+	notifications, err := s.GetNotificationsAfter(context.Background(), userID, seq, 10)
+	if err != nil {
+		t.Fatalf("GetNotificationsAfter: %v", err)
+	}
+
+	return notifications
+}
+
+// A change that takes its seq first and commits last must not be skipped by a stream that already
+// read the change committed before it.
+func TestNotificationSeqCommitOrder(t *testing.T) {
+	db := testDB(t)
+	s := NewNotificationStorage(db)
+	ctx := context.Background()
+
+	userID := createTestUser(t, db)
+	first := createTestUser(t, db)
+	second := createTestUser(t, db)
+
+	// Transaction A adds a follow notification and takes its seq, but doesn't commit yet.
+	txA, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		t.Fatalf("begin: %v", err)
+	}
+	defer txA.Rollback()
+
+	var groupA int
+
+	err = txA.QueryRow(
+		`INSERT INTO "notification" (user_id, type) VALUES ($1, $2) RETURNING id`,
+		userID,
+		models.NotificationFollow,
+	).Scan(&groupA)
+	if err != nil {
+		t.Fatalf("insert: %v", err)
+	}
+
+	if _, err := txA.Exec(`INSERT INTO "notification_actor" (notification_id, actor_id) VALUES ($1, $2)`, groupA, first); err != nil {
+		t.Fatalf("insert actor: %v", err)
+	}
+
+	seqA, err := touch(ctx, txA, userID, groupA)
+	if err != nil {
+		t.Fatalf("touch: %v", err)
+	}
+
+	// Transaction B adds a like notification of the same user meanwhile.
+	done := make(chan error, 1)
+	go func() {
+		done <- s.AddActor(ctx, userID, models.NotificationLike, nil, second)
+	}()
+
+	select {
+	case err := <-done:
+		t.Fatalf("B committed while A holds seq %d, err %v", seqA, err)
+	case <-time.After(300 * time.Millisecond):
+	}
+
+	if got := notificationsAfter(t, s, userID, 0); len(got) != 0 {
+		t.Fatalf("stream sees %d changes before any commit", len(got))
+	}
+
+	if err := txA.Commit(); err != nil {
+		t.Fatalf("commit: %v", err)
+	}
+
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatalf("AddActor: %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("B didn't commit after A")
+	}
+
+	got := notificationsAfter(t, s, userID, 0)
+	if len(got) != 2 {
+		t.Fatalf("got %d changes, want 2", len(got))
+	}
+
+	if got[0].ID != groupA || got[0].Seq != seqA {
+		t.Errorf("first change = group %d seq %d, want group %d seq %d", got[0].ID, got[0].Seq, groupA, seqA)
+	}
+
+	if got[1].Seq <= seqA {
+		t.Errorf("seq of B = %d, want greater than %d", got[1].Seq, seqA)
+	}
+
+	latest, err := s.GetLatestSeq(ctx, userID)
+	if err != nil {
+		t.Fatalf("GetLatestSeq: %v", err)
+	}
+
+	if latest != got[1].Seq {
+		t.Errorf("latest seq = %d, want %d", latest, got[1].Seq)
+	}
+}
+
+func TestNotificationChangesReachStream(t *testing.T) {
+	db := testDB(t)
+	s := NewNotificationStorage(db)
+	ctx := context.Background()
+
+	userID := createTestUser(t, db)
+	first := createTestUser(t, db)
+	second := createTestUser(t, db)
+
+	for _, actorID := range []int{first, second} {
+		if err := s.AddActor(ctx, userID, models.NotificationFollow, nil, actorID); err != nil {
+			t.Fatalf("AddActor: %v", err)
+		}
+	}
+
+	seq, err := s.GetLatestSeq(ctx, userID)
+	if err != nil {
+		t.Fatalf("GetLatestSeq: %v", err)
+	}
+
+	steps := []struct {
+		name       string
+		change     func() error
+		actorCount int
+		read       bool
+	}{
+		{
+			name:       "group shrinks",
+			change:     func() error { return s.RemoveActor(ctx, userID, models.NotificationFollow, nil, first) },
+			actorCount: 1,
+		},
+		{
+			name:       "group emptied",
+			change:     func() error { return s.RemoveActor(ctx, userID, models.NotificationFollow, nil, second) },
+			actorCount: 0,
+		},
+		{
+			name:       "group refilled",
+			change:     func() error { return s.AddActor(ctx, userID, models.NotificationFollow, nil, first) },
+			actorCount: 1,
+		},
+		{
+			name: "group read",
+			change: func() error {
+				_, err := s.MarkRead(ctx, userID, nil)
+
+				return err
+			},
+			actorCount: 1,
+			read:       true,
+		},
+	}
+
+	for _, step := range steps {
+		if err := step.change(); err != nil {
+			t.Fatalf("%s: %v", step.name, err)
+		}
+
+		got := notificationsAfter(t, s, userID, seq)
+		if len(got) != 1 {
+			t.Fatalf("%s: got %d changes, want 1", step.name, len(got))
+		}
+
+		if got[0].ActorCount != step.actorCount || got[0].Read != step.read {
+			t.Errorf("%s: actors %d read %v, want actors %d read %v", step.name, got[0].ActorCount, got[0].Read, step.actorCount, step.read)
+		}
+
+		seq = got[0].Seq
+	}
+
+	var events int
+
+	err = db.QueryRow(
+		`
+		SELECT COUNT(*) FROM "outbox"
+		WHERE convert_from(payload, 'UTF8')::jsonb->>'type' = 'notification.updated'
+		  AND convert_from(payload, 'UTF8')::jsonb->>'key' = $1`,
+		fmt.Sprint(userID),
+	).Scan(&events)
+	if err != nil {
+		t.Fatalf("count events: %v", err)
+	}
+
+	// Two AddActor calls before the steps and one event per step.
+	if events != 2+len(steps) {
+		t.Errorf("enqueued %d events, want %d", events, 2+len(steps))
+	}
+}
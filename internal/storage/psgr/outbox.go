@@ -0,0 +1,141 @@
+package psgr
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	k "kirkagram/internal/kafka"
+	"kirkagram/internal/models"
+	"kirkagram/internal/storage"
+)
+
+type OutboxStorage struct {
+	db *sql.DB
+}
+
+func NewOutboxStorage(db *sql.DB) *OutboxStorage {
+	return &OutboxStorage{db: db}
+}
+
+// PublishPending hands the oldest pending messages to publish one by one and deletes the published ones.
+// Rows are locked with SKIP LOCKED, so several relays never publish the same batch. The batch stops at the
+// first failure to keep the order of events, the failure is recorded on the row and returned.
+//
+// A message failing with storage.ErrUnpublishable, or for the maxAttempts time, is parked instead:
+// it is skipped from then on and the batch goes on. It returns the numbers of published and parked messages.
+func (o *OutboxStorage) PublishPending(ctx context.Context, limit int, maxAttempts int, publish func(msg models.OutboxMessage) error) (int, int, error) {
+	const op = "storage.psgr.outbox.PublishPending"
+
+	tx, err := o.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, 0, fmt.Errorf("%s: %w", op, err)
+	}
+	defer tx.Rollback()
+
+	rows, err := tx.QueryContext(ctx,
+		`
+		SELECT id, topic, payload, attempts, created_at
+		FROM "outbox"
+		WHERE parked_at IS NULL
+		ORDER BY id
+		LIMIT $1
+		FOR UPDATE SKIP LOCKED`,
+		limit,
+	)
+	if err != nil {
+		return 0, 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	messages := []models.OutboxMessage{}
+
+	for rows.Next() {
+		var msg models.OutboxMessage
+
+		if err := rows.Scan(&msg.ID, &msg.Topic, &msg.Payload, &msg.Attempts, &msg.CreatedAt); err != nil {
+			rows.Close()
+
+			return 0, 0, fmt.Errorf("%s: %w", op, err)
+		}
+
+		messages = append(messages, msg)
+	}
+	rows.Close()
+
+	if err := rows.Err(); err != nil {
+		return 0, 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	published, parked := 0, 0
+	var publishErr error
+
+	for _, msg := range messages {
+		if publishErr = publish(msg); publishErr != nil {
+			park := errors.Is(publishErr, storage.ErrUnpublishable) || msg.Attempts+1 >= maxAttempts
+
+			_, err := tx.ExecContext(ctx,
+				`
+				UPDATE "outbox"
+				SET attempts = attempts + 1,
+					last_error = $1,
+					parked_at = CASE WHEN $3 THEN CURRENT_TIMESTAMP END
+				WHERE id = $2`,
+				publishErr.Error(),
+				msg.ID,
+				park,
+			)
+			if err != nil {
+				return 0, 0, fmt.Errorf("%s: %w", op, err)
+			}
+
+			if park {
+				parked++
+				publishErr = nil
+
+				continue
+			}
+
+			break
+		}
+
+		if _, err := tx.ExecContext(ctx, `DELETE FROM "outbox" WHERE id = $1`, msg.ID); err != nil {
+			return 0, 0, fmt.Errorf("%s: %w", op, err)
+		}
+
+		published++
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if publishErr != nil {
+		return published, parked, fmt.Errorf("%s: %w", op, publishErr)
+	}
+
+	return published, parked, nil
+}
+
+// enqueue wraps the payload into an event envelope and stores it in the outbox as part of tx,
+// so the event is published only if the change is committed.
+func enqueue(ctx context.Context, tx *sql.Tx, eventType string, actorID int, key string, payload any) error {
+	event, err := k.NewEvent(eventType, actorID, key, payload)
+	if err != nil {
+		return err
+	}
+
+	topic, err := event.Topic()
+	if err != nil {
+		return err
+	}
+
+	value, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.ExecContext(ctx, `INSERT INTO "outbox" (topic, payload) VALUES ($1, $2)`, topic, value)
+
+	return err
+}
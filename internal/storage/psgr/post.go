@@ -1,13 +1,19 @@
 package psgr
 
 import (
+	"context"
 	"database/sql"
 	"errors"
 	"fmt"
+	k "kirkagram/internal/kafka"
+	"kirkagram/internal/lib/pagination"
 	"kirkagram/internal/models"
 	"kirkagram/internal/storage"
+	"strconv"
 )
 
+const postColumns = `id, user_id, image_url, caption, created_at, updated_at`
+
 type PostStorage struct {
 	db *sql.DB
 }
@@ -16,10 +22,21 @@ func NewPostStorage(db *sql.DB) *PostStorage {
 	return &PostStorage{db: db}
 }
 
-func (p *PostStorage) DeletePost(ID int64) error {
+func (p *PostStorage) DeletePost(ctx context.Context, ID int64) error {
 	const op = "storage.psgr.post.DeletePost"
 
-	exec, err := p.db.Exec("DELETE FROM post WHERE id=$1", ID)
+	tx, err := p.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	defer tx.Rollback()
+
+	var deleted models.Posts
+
+	err = tx.QueryRowContext(ctx,
+		`DELETE FROM post WHERE id=$1 RETURNING `+postColumns,
+		ID,
+	).Scan(&deleted.ID, &deleted.UserID, &deleted.ImageURL, &deleted.Caption, &deleted.CreatedAt, &deleted.UpdatedAt)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			return storage.ErrPostNotFound
@@ -27,105 +44,135 @@ func (p *PostStorage) DeletePost(ID int64) error {
 		return fmt.Errorf("%s: %v", op, err)
 	}
 
-	num, err := exec.RowsAffected()
-	if err != nil {
-		return fmt.Errorf("%s: %v", op, err)
+	// Only the author can delete a post, so the author is the actor.
+	if err := enqueue(ctx, tx, k.PostDeleted, deleted.UserID, strconv.Itoa(deleted.ID), deleted); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
 	}
 
-	if num == 0 {
-		return storage.ErrPostNotFound
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
 	}
 
 	return nil
 }
 
-func (p *PostStorage) GetAllPostsByUserID(userID int64) (*[]models.Posts, error) {
+func (p *PostStorage) GetAllPostsByUserID(ctx context.Context, userID int64, after *models.Cursor, limit int) ([]models.Posts, *models.Cursor, error) {
 	const op = "storage.psgr.post.getAllPostsByUserID"
 
-	rows, err := p.db.Query("SELECT * FROM post WHERE user_id=$1", userID)
+	var rows *sql.Rows
+	var err error
+
+	if after == nil {
+		rows, err = p.db.QueryContext(ctx,
+			`SELECT `+postColumns+` FROM post WHERE user_id = $1 ORDER BY created_at DESC, id DESC LIMIT $2`,
+			userID,
+			limit+1,
+		)
+	} else {
+		rows, err = p.db.QueryContext(ctx,
+			`
+			SELECT `+postColumns+` FROM post
+			WHERE user_id = $1 AND (created_at, id) < ($3, $4)
+			ORDER BY created_at DESC, id DESC
+			LIMIT $2`,
+			userID,
+			limit+1,
+			after.CreatedAt,
+			after.ID,
+		)
+	}
 	if err != nil {
-		if errors.Is(err, sql.ErrNoRows) {
-			return nil, storage.ErrPostNotFound
-		}
-
-		return nil, fmt.Errorf("%s: %w", op, err)
+		return nil, nil, fmt.Errorf("%s: %w", op, err)
 	}
 
-	var posts []models.Posts
-	for rows.Next() {
-		var post models.Posts
-		err = rows.Scan(&post.ID, &post.UserID, &post.ImageURL, &post.Caption, &post.CreatedAt, &post.UpdatedAt)
-		if err != nil {
-			return nil, fmt.Errorf("%s: %w", op, err)
-		}
-
-		posts = append(posts, post)
+	posts, err := scanPosts(rows)
+	if err != nil {
+		return nil, nil, fmt.Errorf("%s: %w", op, err)
 	}
 
-	return &posts, nil
+	posts, next := pagination.TrimPage(posts, limit, postCursor)
+
+	return posts, next, nil
 }
 
-func (p *PostStorage) CreatePost(post models.CreatePostRequest) error {
+func (p *PostStorage) CreatePost(ctx context.Context, post models.CreatePostRequest) (*models.Posts, error) {
 	const op = "storage.psgr.post.CreatePost"
 
-	exec, err := p.db.Exec(
+	tx, err := p.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	defer tx.Rollback()
+
+	var created models.Posts
+
+	err = tx.QueryRowContext(ctx,
 		`
 		INSERT INTO "post" (user_id, image_url, caption)
-		VALUES ($1, $2, $3)`,
+		VALUES ($1, $2, $3)
+		RETURNING id, user_id, image_url, caption, created_at, updated_at`,
 		post.UserID,
 		post.ImageURL,
 		post.Caption,
-	)
-
+	).Scan(&created.ID, &created.UserID, &created.ImageURL, &created.Caption, &created.CreatedAt, &created.UpdatedAt)
 	if err != nil {
-		return fmt.Errorf("%s: %w", op, err)
+		return nil, fmt.Errorf("%s: %w", op, err)
 	}
 
-	num, err := exec.RowsAffected()
-	if err != nil {
-		return fmt.Errorf("%s: %w", op, err)
+	if err := enqueue(ctx, tx, k.PostCreated, created.UserID, strconv.Itoa(created.ID), created); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
 	}
 
-	if num == 0 {
-		return storage.ErrPostExists
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
 	}
 
-	return nil
+	return &created, nil
 }
 
-func (p *PostStorage) GetAllPosts() (*[]models.Posts, error) {
+func (p *PostStorage) GetAllPosts(ctx context.Context, after *models.Cursor, limit int) ([]models.Posts, *models.Cursor, error) {
 	const op = "storage.psgr.post.GetAllPosts"
 
-	rows, err := p.db.Query("SELECT * FROM post")
+	var rows *sql.Rows
+	var err error
+
+	if after == nil {
+		rows, err = p.db.QueryContext(ctx,
+			`SELECT `+postColumns+` FROM post ORDER BY created_at DESC, id DESC LIMIT $1`,
+			limit+1,
+		)
+	} else {
+		rows, err = p.db.QueryContext(ctx,
+			`
+			SELECT `+postColumns+` FROM post
+			WHERE (created_at, id) < ($2, $3)
+			ORDER BY created_at DESC, id DESC
+			LIMIT $1`,
+			limit+1,
+			after.CreatedAt,
+			after.ID,
+		)
+	}
 	if err != nil {
-		return nil, fmt.Errorf("%s: %w", op, err)
+		return nil, nil, fmt.Errorf("%s: %w", op, err)
 	}
 
-	var posts []models.Posts
-
-	for rows.Next() {
-		var post models.Posts
-
-		if err := rows.Scan(&post.ID, &post.UserID, &post.ImageURL, &post.Caption, &post.CreatedAt, &post.UpdatedAt); err != nil {
-			return nil, fmt.Errorf("%s: %w", op, err)
-		}
-
-		posts = append(posts, post)
+	posts, err := scanPosts(rows)
+	if err != nil {
+		return nil, nil, fmt.Errorf("%s: %w", op, err)
 	}
 
-	if err := rows.Err(); err != nil {
-		return nil, fmt.Errorf("%s: %w", op, err)
-	}
+	posts, next := pagination.TrimPage(posts, limit, postCursor)
 
-	return &posts, nil
+	return posts, next, nil
 }
 
-func (p *PostStorage) GetPostByID(ID int64) (*models.Posts, error) {
+func (p *PostStorage) GetPostByID(ctx context.Context, ID int64) (*models.Posts, error) {
 	const op = "storage.psgr.post.GetPostByID"
 
 	var post models.Posts
 
-	err := p.db.QueryRow(
+	err := p.db.QueryRowContext(ctx,
 		"SELECT * FROM post WHERE id = $1",
 		ID,
 	).Scan(&post.ID, &post.UserID, &post.ImageURL, &post.Caption, &post.CreatedAt, &post.UpdatedAt)
@@ -139,3 +186,29 @@ func (p *PostStorage) GetPostByID(ID int64) (*models.Posts, error) {
 
 	return &post, nil
 }
+
+func scanPosts(rows *sql.Rows) ([]models.Posts, error) {
+	defer rows.Close()
+
+	posts := []models.Posts{}
+
+	for rows.Next() {
+		var post models.Posts
+
+		if err := rows.Scan(&post.ID, &post.UserID, &post.ImageURL, &post.Caption, &post.CreatedAt, &post.UpdatedAt); err != nil {
+			return nil, err
+		}
+
+		posts = append(posts, post)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return posts, nil
+}
+
+func postCursor(post models.Posts) models.Cursor {
+	return models.Cursor{CreatedAt: post.CreatedAt, ID: post.ID}
+}
@@ -0,0 +1,44 @@
+package psgr
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+)
+
+type StatsStorage struct {
+	db *sql.DB
+}
+
+func NewStatsStorage(db *sql.DB) *StatsStorage {
+	return &StatsStorage{db: db}
+}
+
+// RefreshUserStats recounts the counters of the user from the source tables, so replaying an event is harmless.
+func (s *StatsStorage) RefreshUserStats(ctx context.Context, userID int) error {
+	const op = "storage.psgr.stats.RefreshUserStats"
+
+	_, err := s.db.ExecContext(ctx,
+		`
+		INSERT INTO "user_stats" (user_id, posts_count, followers_count, following_count, updated_at)
+		SELECT
+			u.id,
+			(SELECT COUNT(*) FROM "post" p WHERE p.user_id = u.id),
+			(SELECT COUNT(*) FROM "follow" f WHERE f.following_id = u.id),
+			(SELECT COUNT(*) FROM "follow" f WHERE f.follower_id = u.id),
+			CURRENT_TIMESTAMP
+		FROM "users" u
+		WHERE u.id = $1
+		ON CONFLICT (user_id) DO UPDATE SET
+			posts_count = EXCLUDED.posts_count,
+			followers_count = EXCLUDED.followers_count,
+			following_count = EXCLUDED.following_count,
+			updated_at = EXCLUDED.updated_at`,
+		userID,
+	)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
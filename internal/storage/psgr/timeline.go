@@ -0,0 +1,99 @@
+package psgr
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"kirkagram/internal/models"
+)
+
+type TimelineStorage struct {
+	db *sql.DB
+}
+
+func NewTimelineStorage(db *sql.DB) *TimelineStorage {
+	return &TimelineStorage{db: db}
+}
+
+// AddPostToFollowers writes the post into the timeline of every follower of its author.
+func (t *TimelineStorage) AddPostToFollowers(ctx context.Context, post models.Posts) (int64, error) {
+	const op = "storage.psgr.timeline.AddPostToFollowers"
+
+	exec, err := t.db.ExecContext(ctx,
+		`
+		INSERT INTO "feed_entry" (user_id, post_id, author_id, created_at)
+		SELECT f.follower_id, p.id, p.user_id, COALESCE(p.created_at, CURRENT_TIMESTAMP)
+		FROM "post" p
+		JOIN "follow" f ON f.following_id = p.user_id
+		WHERE p.id = $1
+		ON CONFLICT DO NOTHING`,
+		post.ID,
+	)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	num, err := exec.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return num, nil
+}
+
+// Backfill copies the latest posts of followingID into the timeline of followerID.
+// Nothing is copied if the follow no longer exists, so a late follow event can't resurrect the posts.
+func (t *TimelineStorage) Backfill(ctx context.Context, followerID int, followingID int, limit int) (int64, error) {
+	const op = "storage.psgr.timeline.Backfill"
+
+	exec, err := t.db.ExecContext(ctx,
+		`
+		INSERT INTO "feed_entry" (user_id, post_id, author_id, created_at)
+		SELECT $1, p.id, p.user_id, COALESCE(p.created_at, CURRENT_TIMESTAMP)
+		FROM "post" p
+		WHERE p.user_id = $2
+		  AND EXISTS (SELECT 1 FROM "follow" WHERE follower_id = $1 AND following_id = $2)
+		ORDER BY p.created_at DESC, p.id DESC
+		LIMIT $3
+		ON CONFLICT DO NOTHING`,
+		followerID,
+		followingID,
+		limit,
+	)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	num, err := exec.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return num, nil
+}
+
+// RemoveAuthor drops every post of followingID from the timeline of followerID
+// unless followerID has followed followingID again by the time the unfollow event is handled.
+func (t *TimelineStorage) RemoveAuthor(ctx context.Context, followerID int, followingID int) (int64, error) {
+	const op = "storage.psgr.timeline.RemoveAuthor"
+
+	exec, err := t.db.ExecContext(ctx,
+		`
+		DELETE FROM "feed_entry"
+		WHERE user_id = $1
+		  AND author_id = $2
+		  AND NOT EXISTS (SELECT 1 FROM "follow" WHERE follower_id = $1 AND following_id = $2)`,
+		followerID,
+		followingID,
+	)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	num, err := exec.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return num, nil
+}
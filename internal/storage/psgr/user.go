@@ -1,12 +1,16 @@
 package psgr
 
 import (
+	"context"
 	"database/sql"
 	"errors"
 	"fmt"
 	"github.com/lib/pq"
+	k "kirkagram/internal/kafka"
+	"kirkagram/internal/lib/pagination"
 	"kirkagram/internal/models"
 	"kirkagram/internal/storage"
+	"strconv"
 )
 
 type UserStorage struct {
@@ -17,10 +21,10 @@ func NewUserStorage(db *sql.DB) *UserStorage {
 	return &UserStorage{db: db}
 }
 
-func (s *UserStorage) CreateUser(user *models.CreateUserRequest) error {
+func (s *UserStorage) CreateUser(ctx context.Context, user *models.CreateUserRequest) error {
 	const op = "storage.psgr.user.CreateUser"
 
-	exec, err := s.db.Exec(
+	exec, err := s.db.ExecContext(ctx,
 		`INSERT INTO "users" (username, email, password) VALUES ($1, $2, $3)`,
 		user.Username,
 		user.Email,
@@ -53,10 +57,29 @@ func (s *UserStorage) CreateUser(user *models.CreateUserRequest) error {
 	return nil
 }
 
-func (s *UserStorage) DeleteUser(ID int64) error {
+func (s *UserStorage) DeleteUser(ctx context.Context, ID int64) error {
 	const op = "storage.psgr.user.DeleteUser"
 
-	exec, err := s.db.Exec(
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	defer tx.Rollback()
+
+	event := models.UserDeletedEvent{ID: int(ID)}
+
+	// Follows are removed by ON DELETE CASCADE, so the connections are read before the user is deleted.
+	event.Followers, err = selectIDs(ctx, tx, `SELECT follower_id FROM "follow" WHERE following_id = $1`, ID)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	event.Following, err = selectIDs(ctx, tx, `SELECT following_id FROM "follow" WHERE follower_id = $1`, ID)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	exec, err := tx.ExecContext(ctx,
 		`DELETE FROM "users" WHERE id=$1`,
 		ID,
 	)
@@ -77,15 +100,23 @@ func (s *UserStorage) DeleteUser(ID int64) error {
 		return storage.ErrUserNotFound
 	}
 
+	if err := enqueue(ctx, tx, k.UserDeleted, event.ID, strconv.Itoa(event.ID), event); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
 	return nil
 }
 
-func (s *UserStorage) UploadProfilePic(userID int, filename string) error {
+func (s *UserStorage) UploadProfilePic(ctx context.Context, userID int, filename string) error {
 	const op = "storage.psgr.user.UploadProfilePic"
 
 	profilePic := fmt.Sprintf("api/photo/%v", filename)
 
-	exec, err := s.db.Exec(
+	exec, err := s.db.ExecContext(ctx,
 		`UPDATE "users" SET "profile_pic" = $1 WHERE "id" = $2`,
 		profilePic,
 		userID,
@@ -109,15 +140,30 @@ func (s *UserStorage) UploadProfilePic(userID int, filename string) error {
 	return nil
 }
 
-func (s *UserStorage) GetByID(ID string) (*models.GetUserResponse, error) {
+func (s *UserStorage) GetByID(ctx context.Context, ID string) (*models.GetUserResponse, error) {
 	const op = "storage.psgr.user.GetUser"
 
 	var user models.GetUserResponse
 
-	row := s.db.QueryRow(
-		`SELECT "id", "email", "username", "bio", "profile_pic" FROM "users" WHERE "id" = $1`,
+	row := s.db.QueryRowContext(ctx,
+		`
+		SELECT
+			u.id, u.email, u.username, u.bio, u.profile_pic,
+			COALESCE(s.posts_count, 0), COALESCE(s.followers_count, 0), COALESCE(s.following_count, 0)
+		FROM "users" u
+		LEFT JOIN "user_stats" s ON s.user_id = u.id
+		WHERE u.id = $1`,
 		ID,
-	).Scan(&user.ID, &user.Email, &user.Username, &user.Bio, &user.ProfilePic)
+	).Scan(
+		&user.ID,
+		&user.Email,
+		&user.Username,
+		&user.Bio,
+		&user.ProfilePic,
+		&user.PostsCount,
+		&user.FollowersCount,
+		&user.FollowingCount,
+	)
 
 	if row != nil {
 		if errors.Is(row, sql.ErrNoRows) {
@@ -130,130 +176,159 @@ func (s *UserStorage) GetByID(ID string) (*models.GetUserResponse, error) {
 	return &user, nil
 }
 
-func (s *UserStorage) Update(updateUser models.UpdateUserRequest) error {
+func (s *UserStorage) GetByEmail(ctx context.Context, email string) (*models.User, error) {
+	const op = "storage.psgr.user.GetByEmail"
+
+	var user models.User
+
+	err := s.db.QueryRowContext(ctx,
+		`SELECT "id", "email", "username", "password" FROM "users" WHERE "email" = $1`,
+		email,
+	).Scan(&user.ID, &user.Email, &user.Username, &user.Password)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, fmt.Errorf("%s: %w", op, storage.ErrUserNotFound)
+		}
+
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return &user, nil
+}
+
+func (s *UserStorage) Update(ctx context.Context, updateUser models.UpdateUserRequest) error {
 	const op = "storage.psgr.user.Update"
 
-	row, err := s.db.Exec(
-		`UPDATE "users" SET "username" = $1, "email" = $2, "bio" = $3 WHERE "id" = $5`,
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	defer tx.Rollback()
+
+	var event models.UserEvent
+
+	err = tx.QueryRowContext(ctx,
+		`
+		UPDATE "users" SET "username" = $1, "email" = $2, "bio" = $3 WHERE "id" = $4
+		RETURNING "id", "username", COALESCE("bio", ''), COALESCE("profile_pic", '')`,
 		updateUser.Username,
 		updateUser.Email,
 		updateUser.Bio,
 		updateUser.ID,
-	)
+	).Scan(&event.ID, &event.Username, &event.Bio, &event.ProfilePic)
 
 	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return fmt.Errorf("%s: %w", op, storage.ErrUserNotFound)
+		}
+
 		return fmt.Errorf("%s: %w", op, err)
 	}
 
-	affectedRows, err := row.RowsAffected()
-	if err != nil {
+	if err := enqueue(ctx, tx, k.UserUpdated, event.ID, strconv.Itoa(event.ID), event); err != nil {
 		return fmt.Errorf("%s: %w", op, err)
 	}
 
-	if affectedRows == 0 {
-		return fmt.Errorf("%s: %w", op, storage.ErrUserNotFound)
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
 	}
 
 	return nil
 }
 
-func (s *UserStorage) GetAllFollowers(userID int) (*[]models.GetAllFollowersResponse, error) {
+func (s *UserStorage) GetAllFollowers(ctx context.Context, userID int, after *models.Cursor, limit int) ([]models.GetAllFollowersResponse, *models.Cursor, error) {
 	const op = "storage.psgr.user.GetAllFollowers"
 
-	rows, err := s.db.Query(
-		`
-		SELECT u.username, u.profile_pic
-		FROM users u
-		JOIN follow as f ON u.id = f.follower_id
-		WHERE f.following_id = $1
-	`,
-		userID,
-	)
-
+	followers, next, err := s.getFollowPage(ctx, `u.id = f.follower_id AND f.following_id = $1`, userID, after, limit)
 	if err != nil {
-		if errors.Is(err, sql.ErrNoRows) {
-			return nil, storage.ErrUserNotFound
-		}
+		return nil, nil, fmt.Errorf("%s: %w", op, err)
+	}
 
-		return nil, fmt.Errorf("%s: %w", op, err)
+	return followers, next, nil
+}
+
+func (s *UserStorage) GetAllFollowing(ctx context.Context, userID int, after *models.Cursor, limit int) ([]models.GetAllFollowersResponse, *models.Cursor, error) {
+	const op = "storage.psgr.user.GetAllFollowing"
+
+	following, next, err := s.getFollowPage(ctx, `u.id = f.following_id AND f.follower_id = $1`, userID, after, limit)
+	if err != nil {
+		return nil, nil, fmt.Errorf("%s: %w", op, err)
 	}
 
-	var followers []models.GetAllFollowersResponse
+	return following, next, nil
+}
 
-	if !rows.Next() {
-		return &[]models.GetAllFollowersResponse{}, nil
-	} else {
-		var follower models.GetAllFollowersResponse
+// getFollowPage lists users joined to the follow table by joinCondition, most recent follows first.
+// The cursor is the ID of the follow row.
+func (s *UserStorage) getFollowPage(ctx context.Context, joinCondition string, userID int, after *models.Cursor, limit int) ([]models.GetAllFollowersResponse, *models.Cursor, error) {
+	type followRow struct {
+		followID int
+		user     models.GetAllFollowersResponse
+	}
 
-		if err := rows.Scan(&follower.Username, &follower.ProfilePic); err != nil {
-			return nil, fmt.Errorf("%s: %w", op, err)
-		}
+	query := `
+		SELECT f.id, u.id, u.username, COALESCE(u.profile_pic, '')
+		FROM users u
+		JOIN follow AS f ON ` + joinCondition
+
+	var rows *sql.Rows
+	var err error
 
-		followers = append(followers, follower)
+	if after == nil {
+		rows, err = s.db.QueryContext(ctx, query+` ORDER BY f.id DESC LIMIT $2`, userID, limit+1)
+	} else {
+		rows, err = s.db.QueryContext(ctx, query+` AND f.id < $3 ORDER BY f.id DESC LIMIT $2`, userID, limit+1, after.ID)
+	}
+	if err != nil {
+		return nil, nil, err
 	}
+	defer rows.Close()
+
+	var page []followRow
 
 	for rows.Next() {
-		var follower models.GetAllFollowersResponse
+		var row followRow
 
-		if err := rows.Scan(&follower.Username, &follower.ProfilePic); err != nil {
-			return nil, fmt.Errorf("%s: %w", op, err)
+		if err := rows.Scan(&row.followID, &row.user.ID, &row.user.Username, &row.user.ProfilePic); err != nil {
+			return nil, nil, err
 		}
 
-		followers = append(followers, follower)
+		page = append(page, row)
 	}
 
 	if err := rows.Err(); err != nil {
-		return nil, fmt.Errorf("%s: %w", op, err)
+		return nil, nil, err
 	}
 
-	return &followers, nil
-}
+	page, next := pagination.TrimPage(page, limit, func(row followRow) models.Cursor {
+		return models.Cursor{ID: row.followID}
+	})
 
-func (s *UserStorage) GetAllFollowing(userID int) (*[]models.GetAllFollowersResponse, error) {
-	const op = "storage.psgr.user.GetAllFollowing"
+	users := make([]models.GetAllFollowersResponse, 0, len(page))
+	for _, row := range page {
+		users = append(users, row.user)
+	}
 
-	rows, err := s.db.Query(`
-		SELECT u.username, u.profile_pic
-		FROM users u
-		JOIN follow as f ON u.id = f.following_id
-		WHERE f.follower_id = $1
-		`, userID)
+	return users, next, nil
+}
 
+func selectIDs(ctx context.Context, tx *sql.Tx, query string, args ...any) ([]int, error) {
+	rows, err := tx.QueryContext(ctx, query, args...)
 	if err != nil {
-		if errors.Is(err, sql.ErrNoRows) {
-			return nil, storage.ErrUserNotFound
-		}
-
-		return nil, fmt.Errorf("%s: %w", op, err)
+		return nil, err
 	}
+	defer rows.Close()
 
-	var followers []models.GetAllFollowersResponse
-
-	if !rows.Next() {
-		return &[]models.GetAllFollowersResponse{}, nil
-	} else {
-		var follower models.GetAllFollowersResponse
-
-		if err := rows.Scan(&follower.Username, &follower.ProfilePic); err != nil {
-			return nil, fmt.Errorf("%s: %w", op, err)
-		}
-
-		followers = append(followers, follower)
-	}
+	IDs := []int{}
 
 	for rows.Next() {
-		var follower models.GetAllFollowersResponse
-
-		if err := rows.Scan(&follower.Username, &follower.ProfilePic); err != nil {
-			return nil, fmt.Errorf("%s: %w", op, err)
+		var ID int
+		if err := rows.Scan(&ID); err != nil {
+			return nil, err
 		}
 
-		followers = append(followers, follower)
-	}
-
-	if err := rows.Err(); err != nil {
-		return nil, fmt.Errorf("%s: %w", op, err)
+		IDs = append(IDs, ID)
 	}
 
-	return &followers, nil
+	return IDs, rows.Err()
 }
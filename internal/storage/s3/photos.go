@@ -1,56 +1,79 @@
 package s3
 
 import (
-	"bytes"
 	"context"
 	"errors"
 	"fmt"
 	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/feature/s3/manager"
 	"github.com/aws/aws-sdk-go-v2/service/s3"
 	"github.com/aws/aws-sdk-go-v2/service/s3/types"
 	"io"
 	"kirkagram/internal/storage"
 )
 
-const bucketName = "kirkagram"
+// uploadConcurrency is the number of parts of one upload sent at once. A photo that isn't seekable
+// is buffered a part at a time, so an upload holds at most uploadConcurrency+1 parts in memory.
+const uploadConcurrency = 2
 
 type PhotoS3Storage struct {
-	client *s3.Client
+	client   *s3.Client
+	uploader *manager.Uploader
+	bucket   string
 }
 
-func NewUserS3Storage(client *s3.Client) *PhotoS3Storage {
-	return &PhotoS3Storage{client: client}
+func NewUserS3Storage(client *s3.Client, bucket string) *PhotoS3Storage {
+	uploader := manager.NewUploader(client, func(u *manager.Uploader) {
+		u.PartSize = manager.MinUploadPartSize
+		u.Concurrency = uploadConcurrency
+	})
+
+	return &PhotoS3Storage{
+		client:   client,
+		uploader: uploader,
+		bucket:   bucket,
+	}
 }
 
-func (u *PhotoS3Storage) GetPhoto(key string) ([]byte, error) {
+func (u *PhotoS3Storage) GetPhoto(ctx context.Context, key string) (io.ReadCloser, int64, error) {
 	const op = "storage.s3.GetPhoto"
 
-	result, err := u.client.GetObject(context.Background(), &s3.GetObjectInput{
-		Bucket: aws.String(bucketName),
+	result, err := u.client.GetObject(ctx, &s3.GetObjectInput{
+		Bucket: aws.String(u.bucket),
 		Key:    aws.String(key),
 	})
 	if err != nil {
 		var nsk *types.NoSuchKey
 		if errors.As(err, &nsk) {
-			return nil, fmt.Errorf("%s: %w", op, storage.ErrNoSuchKey)
+			return nil, 0, fmt.Errorf("%s: %w", op, storage.ErrNoSuchKey)
 		}
 
-		return nil, fmt.Errorf("%s: %w", op, err)
+		return nil, 0, fmt.Errorf("%s: %w", op, err)
 	}
 
-	defer result.Body.Close()
+	size := int64(-1)
+	if result.ContentLength != nil {
+		size = *result.ContentLength
+	}
 
-	return io.ReadAll(result.Body)
+	return result.Body, size, nil
 }
 
-func (u *PhotoS3Storage) UploadPhoto(key string, data []byte) error {
+// UploadPhoto sends a photo larger than a part as a multipart upload. A multipart form file is
+// seekable, its parts are read straight from the file.
+func (u *PhotoS3Storage) UploadPhoto(ctx context.Context, key string, body io.Reader, size int64) error {
 	const op = "storage.s3.UploadPhoto"
 
-	_, err := u.client.PutObject(context.Background(), &s3.PutObjectInput{
-		Bucket: aws.String(bucketName),
+	input := &s3.PutObjectInput{
+		Bucket: aws.String(u.bucket),
 		Key:    aws.String(key),
-		Body:   bytes.NewReader(data),
-	})
+		Body:   body,
+	}
+	if size >= 0 {
+		input.ContentLength = aws.Int64(size)
+	}
+
+	_, err := u.uploader.Upload(ctx, input)
 	if err != nil {
 		return fmt.Errorf("%s: %w", op, err)
 	}
@@ -0,0 +1,64 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"kirkagram/internal/models"
+	"kirkagram/internal/storage"
+
+	sqlite3 "modernc.org/sqlite/lib"
+)
+
+type FollowStorage struct {
+	db *sql.DB
+}
+
+func NewFollowStorage(db *sql.DB) *FollowStorage {
+	return &FollowStorage{db: db}
+}
+
+func (f *FollowStorage) FollowByID(ctx context.Context, req models.FollowRequest) error {
+	const op = "storage.sqlite.follow.FollowByID"
+
+	if req.FollowerID == req.FollowingID {
+		return fmt.Errorf("%s: %w", op, storage.SelfFollowError)
+	}
+
+	_, err := f.db.ExecContext(ctx,
+		`INSERT INTO "follow" ("follower_id", "following_id") VALUES ($1, $2)`,
+		req.FollowerID,
+		req.FollowingID,
+	)
+	if err != nil {
+		switch {
+		case isConstraint(err, sqlite3.SQLITE_CONSTRAINT_UNIQUE):
+			return fmt.Errorf("%s: %w", op, storage.ErrAlreadyFollowed)
+		case isConstraint(err, sqlite3.SQLITE_CONSTRAINT_FOREIGNKEY):
+			return fmt.Errorf("%s: %w", op, storage.ErrUserNotFound)
+		}
+
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+func (f *FollowStorage) UnFollowByID(ctx context.Context, req models.FollowRequest) error {
+	const op = "storage.sqlite.follow.UnFollowByID"
+
+	if req.FollowerID == req.FollowingID {
+		return fmt.Errorf("%s: %w", op, storage.SelfUnFollowError)
+	}
+
+	_, err := f.db.ExecContext(ctx,
+		`DELETE FROM "follow" WHERE follower_id = $1 AND following_id = $2`,
+		req.FollowerID,
+		req.FollowingID,
+	)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
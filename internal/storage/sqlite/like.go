@@ -0,0 +1,77 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"kirkagram/internal/models"
+	"kirkagram/internal/storage"
+
+	sqlite3 "modernc.org/sqlite/lib"
+)
+
+type LikeStorage struct {
+	db *sql.DB
+}
+
+func NewLikeStorage(db *sql.DB) *LikeStorage {
+	return &LikeStorage{db: db}
+}
+
+func (l *LikeStorage) GetLikesByID(ctx context.Context, postID int) (models.LikeResponse, error) {
+	const op = "storage.sqlite.like.GetLikesByID"
+
+	var count int
+
+	err := l.db.QueryRowContext(ctx, `SELECT COUNT(*) FROM "like" WHERE post_id = $1`, postID).Scan(&count)
+	if err != nil {
+		return models.LikeResponse{Count: 0}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return models.LikeResponse{Count: count}, nil
+}
+
+func (l *LikeStorage) UnlikePostByID(ctx context.Context, likeReq *models.LikeRequest) error {
+	const op = "storage.sqlite.like.UnlikePostByID"
+
+	exec, err := l.db.ExecContext(ctx,
+		`DELETE FROM "like" WHERE user_id = $1 AND post_id = $2`,
+		likeReq.UserID,
+		likeReq.PostID,
+	)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	num, err := exec.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	if num == 0 {
+		return fmt.Errorf("%s: %w", op, storage.ErrLikeNotFound)
+	}
+
+	return nil
+}
+
+func (l *LikeStorage) LikePostByID(ctx context.Context, likeReq *models.LikeRequest) error {
+	const op = "storage.sqlite.like.LikePostByID"
+
+	_, err := l.db.ExecContext(ctx,
+		`INSERT INTO "like" (user_id, post_id) VALUES ($1, $2)`,
+		likeReq.UserID,
+		likeReq.PostID,
+	)
+	if err != nil {
+		switch {
+		case isConstraint(err, sqlite3.SQLITE_CONSTRAINT_UNIQUE):
+			return storage.ErrPostAlreadyLiked
+		case isConstraint(err, sqlite3.SQLITE_CONSTRAINT_FOREIGNKEY):
+			return fmt.Errorf("%s: %w", op, storage.ErrPostNotFound)
+		}
+
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
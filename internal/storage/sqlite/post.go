@@ -0,0 +1,176 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"kirkagram/internal/lib/pagination"
+	"kirkagram/internal/models"
+	"kirkagram/internal/storage"
+)
+
+const postColumns = `id, user_id, image_url, COALESCE(caption, ''), created_at, updated_at`
+
+// PostStorage pages posts by ID alone: IDs grow with creation time, so the order matches created_at
+// without comparing timestamps stored as text.
+type PostStorage struct {
+	db *sql.DB
+}
+
+func NewPostStorage(db *sql.DB) *PostStorage {
+	return &PostStorage{db: db}
+}
+
+func (p *PostStorage) DeletePost(ctx context.Context, ID int64) error {
+	const op = "storage.sqlite.post.DeletePost"
+
+	exec, err := p.db.ExecContext(ctx, `DELETE FROM "post" WHERE id = $1`, ID)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	num, err := exec.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if num == 0 {
+		return storage.ErrPostNotFound
+	}
+
+	return nil
+}
+
+func (p *PostStorage) GetAllPostsByUserID(ctx context.Context, userID int64, after *models.Cursor, limit int) ([]models.Posts, *models.Cursor, error) {
+	const op = "storage.sqlite.post.GetAllPostsByUserID"
+
+	var rows *sql.Rows
+	var err error
+
+	if after == nil {
+		rows, err = p.db.QueryContext(ctx,
+			`SELECT `+postColumns+` FROM "post" WHERE user_id = $1 ORDER BY id DESC LIMIT $2`,
+			userID,
+			limit+1,
+		)
+	} else {
+		rows, err = p.db.QueryContext(ctx,
+			`SELECT `+postColumns+` FROM "post" WHERE user_id = $1 AND id < $3 ORDER BY id DESC LIMIT $2`,
+			userID,
+			limit+1,
+			after.ID,
+		)
+	}
+	if err != nil {
+		return nil, nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	posts, err := scanPosts(rows)
+	if err != nil {
+		return nil, nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	posts, next := pagination.TrimPage(posts, limit, postCursor)
+
+	return posts, next, nil
+}
+
+func (p *PostStorage) CreatePost(ctx context.Context, post models.CreatePostRequest) (*models.Posts, error) {
+	const op = "storage.sqlite.post.CreatePost"
+
+	var created models.Posts
+
+	err := p.db.QueryRowContext(ctx,
+		`
+		INSERT INTO "post" (user_id, image_url, caption)
+		VALUES ($1, $2, $3)
+		RETURNING `+postColumns,
+		post.UserID,
+		post.ImageURL,
+		post.Caption,
+	).Scan(&created.ID, &created.UserID, &created.ImageURL, &created.Caption, &created.CreatedAt, &created.UpdatedAt)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return &created, nil
+}
+
+func (p *PostStorage) GetAllPosts(ctx context.Context, after *models.Cursor, limit int) ([]models.Posts, *models.Cursor, error) {
+	const op = "storage.sqlite.post.GetAllPosts"
+
+	var rows *sql.Rows
+	var err error
+
+	if after == nil {
+		rows, err = p.db.QueryContext(ctx,
+			`SELECT `+postColumns+` FROM "post" ORDER BY id DESC LIMIT $1`,
+			limit+1,
+		)
+	} else {
+		rows, err = p.db.QueryContext(ctx,
+			`SELECT `+postColumns+` FROM "post" WHERE id < $2 ORDER BY id DESC LIMIT $1`,
+			limit+1,
+			after.ID,
+		)
+	}
+	if err != nil {
+		return nil, nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	posts, err := scanPosts(rows)
+	if err != nil {
+		return nil, nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	posts, next := pagination.TrimPage(posts, limit, postCursor)
+
+	return posts, next, nil
+}
+
+func (p *PostStorage) GetPostByID(ctx context.Context, ID int64) (*models.Posts, error) {
+	const op = "storage.sqlite.post.GetPostByID"
+
+	var post models.Posts
+
+	err := p.db.QueryRowContext(ctx,
+		`SELECT `+postColumns+` FROM "post" WHERE id = $1`,
+		ID,
+	).Scan(&post.ID, &post.UserID, &post.ImageURL, &post.Caption, &post.CreatedAt, &post.UpdatedAt)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, storage.ErrPostNotFound
+		}
+
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return &post, nil
+}
+
+func scanPosts(rows *sql.Rows) ([]models.Posts, error) {
+	defer rows.Close()
+
+	posts := []models.Posts{}
+
+	for rows.Next() {
+		var post models.Posts
+
+		if err := rows.Scan(&post.ID, &post.UserID, &post.ImageURL, &post.Caption, &post.CreatedAt, &post.UpdatedAt); err != nil {
+			return nil, err
+		}
+
+		posts = append(posts, post)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return posts, nil
+}
+
+func postCursor(post models.Posts) models.Cursor {
+	return models.Cursor{CreatedAt: post.CreatedAt, ID: post.ID}
+}
@@ -0,0 +1,59 @@
+-- Схема для локального запуска без Postgres: пользователи, посты, лайки, подписки и refresh-токены
+CREATE TABLE IF NOT EXISTS "users" (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    username VARCHAR(50) NOT NULL UNIQUE,
+    email VARCHAR(100) NOT NULL UNIQUE,
+    password VARCHAR(255) NOT NULL, -- Хэш пароля
+    bio TEXT,
+    profile_pic VARCHAR(255)
+);
+
+CREATE TABLE IF NOT EXISTS "post" (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    user_id INTEGER NOT NULL,
+    image_url TEXT NOT NULL,
+    caption TEXT,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS post_user_idx ON "post"(user_id, id DESC);
+
+CREATE TABLE IF NOT EXISTS "like" (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    user_id INTEGER NOT NULL,
+    post_id INTEGER NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
+    FOREIGN KEY (post_id) REFERENCES post(id) ON DELETE CASCADE,
+    UNIQUE (user_id, post_id) -- Пользователь может лайкнуть пост только один раз
+);
+
+CREATE INDEX IF NOT EXISTS like_post_idx ON "like"(post_id);
+
+CREATE TABLE IF NOT EXISTS "follow" (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    follower_id INTEGER NOT NULL,
+    following_id INTEGER NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (follower_id) REFERENCES users(id) ON DELETE CASCADE,
+    FOREIGN KEY (following_id) REFERENCES users(id) ON DELETE CASCADE,
+    UNIQUE (follower_id, following_id)
+);
+
+CREATE INDEX IF NOT EXISTS follow_following_idx ON follow(following_id);
+
+CREATE TABLE IF NOT EXISTS "refresh_token" (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    user_id INTEGER NOT NULL,
+    family_id VARCHAR(32) NOT NULL,         -- Все токены, полученные ротацией из одного логина
+    token_hash VARCHAR(64) NOT NULL UNIQUE, -- SHA-256 от токена, сам токен не храним
+    expires_at TIMESTAMP NOT NULL,
+    revoked_at TIMESTAMP,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS refresh_token_family_idx ON refresh_token(family_id);
+CREATE INDEX IF NOT EXISTS refresh_token_user_idx ON refresh_token(user_id);
@@ -0,0 +1,62 @@
+package sqlite
+
+import (
+	"database/sql"
+	_ "embed"
+	"errors"
+	"fmt"
+	"kirkagram/internal/config"
+	"log/slog"
+	"net/url"
+	"os"
+	"path/filepath"
+
+	"modernc.org/sqlite"
+)
+
+// schema is applied on every start, all statements are idempotent.
+//
+//go:embed schema.sql
+var schema string
+
+// New opens the SQLite database in cfg.StoragePath, creating the file and the schema if needed.
+// The driver is pure Go, so no database server or cgo toolchain is required.
+func New(cfg *config.Config, log *slog.Logger) *sql.DB {
+	const op = "storage.sqlite.New"
+
+	if dir := filepath.Dir(cfg.StoragePath); dir != "" {
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			panic(fmt.Errorf("%s: %w", op, err))
+		}
+	}
+
+	// Foreign keys are off in SQLite by default. Immediate transactions take the write lock up front,
+	// so concurrent writers wait for busy_timeout instead of failing on a lock upgrade.
+	params := url.Values{}
+	params.Add("_pragma", "foreign_keys(1)")
+	params.Add("_pragma", "busy_timeout(5000)")
+	params.Add("_pragma", "journal_mode(WAL)")
+	params.Add("_txlock", "immediate")
+
+	db, err := sql.Open("sqlite", "file:"+cfg.StoragePath+"?"+params.Encode())
+	if err != nil {
+		panic(fmt.Errorf("%s: %w", op, err))
+	}
+
+	if _, err := db.Exec(schema); err != nil {
+		db.Close()
+
+		panic(fmt.Errorf("%s: %w", op, err))
+	}
+
+	log.Info("Opened SQLite database", slog.String("path", cfg.StoragePath))
+
+	return db
+}
+
+// isConstraint reports whether err is a violation of the constraint kind, e.g. sqlite3.SQLITE_CONSTRAINT_UNIQUE.
+func isConstraint(err error, code int) bool {
+	var sqliteErr *sqlite.Error
+
+	return errors.As(err, &sqliteErr) && sqliteErr.Code() == code
+}
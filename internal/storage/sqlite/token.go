@@ -0,0 +1,150 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"kirkagram/internal/models"
+	"kirkagram/internal/storage"
+	"time"
+)
+
+type TokenStorage struct {
+	db *sql.DB
+}
+
+func NewTokenStorage(db *sql.DB) *TokenStorage {
+	return &TokenStorage{db: db}
+}
+
+func (t *TokenStorage) SaveRefreshToken(ctx context.Context, token models.RefreshToken) error {
+	const op = "storage.sqlite.token.SaveRefreshToken"
+
+	_, err := t.db.ExecContext(ctx,
+		`INSERT INTO "refresh_token" (user_id, family_id, token_hash, expires_at) VALUES ($1, $2, $3, $4)`,
+		token.UserID,
+		token.FamilyID,
+		token.TokenHash,
+		token.ExpiresAt,
+	)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+// RotateRefreshToken revokes the token with oldHash and stores next in the same family.
+// Presenting an already revoked token revokes the whole family and returns storage.ErrRefreshTokenReused.
+func (t *TokenStorage) RotateRefreshToken(ctx context.Context, oldHash string, next models.RefreshToken) (int, error) {
+	const op = "storage.sqlite.token.RotateRefreshToken"
+
+	tx, err := t.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+	defer tx.Rollback()
+
+	var current models.RefreshToken
+	var revokedAt sql.NullTime
+
+	err = tx.QueryRowContext(ctx,
+		`SELECT id, user_id, family_id, expires_at, revoked_at FROM "refresh_token" WHERE token_hash = $1`,
+		oldHash,
+	).Scan(&current.ID, &current.UserID, &current.FamilyID, &current.ExpiresAt, &revokedAt)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return 0, fmt.Errorf("%s: %w", op, storage.ErrRefreshTokenNotFound)
+		}
+
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if revokedAt.Valid {
+		_, err = tx.ExecContext(ctx,
+			`UPDATE "refresh_token" SET revoked_at = CURRENT_TIMESTAMP WHERE family_id = $1 AND revoked_at IS NULL`,
+			current.FamilyID,
+		)
+		if err != nil {
+			return 0, fmt.Errorf("%s: %w", op, err)
+		}
+
+		if err := tx.Commit(); err != nil {
+			return 0, fmt.Errorf("%s: %w", op, err)
+		}
+
+		return 0, fmt.Errorf("%s: %w", op, storage.ErrRefreshTokenReused)
+	}
+
+	if time.Now().After(current.ExpiresAt) {
+		return 0, fmt.Errorf("%s: %w", op, storage.ErrRefreshTokenExpired)
+	}
+
+	_, err = tx.ExecContext(ctx,
+		`UPDATE "refresh_token" SET revoked_at = CURRENT_TIMESTAMP WHERE id = $1`,
+		current.ID,
+	)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	_, err = tx.ExecContext(ctx,
+		`INSERT INTO "refresh_token" (user_id, family_id, token_hash, expires_at) VALUES ($1, $2, $3, $4)`,
+		current.UserID,
+		current.FamilyID,
+		next.TokenHash,
+		next.ExpiresAt,
+	)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return current.UserID, nil
+}
+
+func (t *TokenStorage) RevokeFamilyByHash(ctx context.Context, tokenHash string) error {
+	const op = "storage.sqlite.token.RevokeFamilyByHash"
+
+	var familyID string
+
+	err := t.db.QueryRowContext(ctx,
+		`SELECT family_id FROM "refresh_token" WHERE token_hash = $1`,
+		tokenHash,
+	).Scan(&familyID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return fmt.Errorf("%s: %w", op, storage.ErrRefreshTokenNotFound)
+		}
+
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	_, err = t.db.ExecContext(ctx,
+		`UPDATE "refresh_token" SET revoked_at = CURRENT_TIMESTAMP WHERE family_id = $1 AND revoked_at IS NULL`,
+		familyID,
+	)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+func (t *TokenStorage) RevokeAllByUserID(ctx context.Context, userID int) error {
+	const op = "storage.sqlite.token.RevokeAllByUserID"
+
+	_, err := t.db.ExecContext(ctx,
+		`UPDATE "refresh_token" SET revoked_at = CURRENT_TIMESTAMP WHERE user_id = $1 AND revoked_at IS NULL`,
+		userID,
+	)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
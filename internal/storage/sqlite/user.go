@@ -0,0 +1,253 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"kirkagram/internal/lib/pagination"
+	"kirkagram/internal/models"
+	"kirkagram/internal/storage"
+	"strings"
+
+	sqlite3 "modernc.org/sqlite/lib"
+)
+
+type UserStorage struct {
+	db *sql.DB
+}
+
+func NewUserStorage(db *sql.DB) *UserStorage {
+	return &UserStorage{db: db}
+}
+
+func (s *UserStorage) CreateUser(ctx context.Context, user *models.CreateUserRequest) error {
+	const op = "storage.sqlite.user.CreateUser"
+
+	_, err := s.db.ExecContext(ctx,
+		`INSERT INTO "users" (username, email, password) VALUES ($1, $2, $3)`,
+		user.Username,
+		user.Email,
+		user.Password,
+	)
+	if err != nil {
+		if isConstraint(err, sqlite3.SQLITE_CONSTRAINT_UNIQUE) {
+			switch {
+			case strings.Contains(err.Error(), "users.email"):
+				return storage.ErrEmailAlreadyRegistered
+			case strings.Contains(err.Error(), "users.username"):
+				return storage.ErrUsernameAlreadyRegistered
+			}
+
+			return storage.ErrUserAlreadyExists
+		}
+
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+func (s *UserStorage) DeleteUser(ctx context.Context, ID int64) error {
+	const op = "storage.sqlite.user.DeleteUser"
+
+	exec, err := s.db.ExecContext(ctx, `DELETE FROM "users" WHERE id = $1`, ID)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	num, err := exec.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if num == 0 {
+		return storage.ErrUserNotFound
+	}
+
+	return nil
+}
+
+func (s *UserStorage) UploadProfilePic(ctx context.Context, userID int, filename string) error {
+	const op = "storage.sqlite.user.UploadProfilePic"
+
+	profilePic := fmt.Sprintf("api/photo/%v", filename)
+
+	exec, err := s.db.ExecContext(ctx,
+		`UPDATE "users" SET "profile_pic" = $1 WHERE "id" = $2`,
+		profilePic,
+		userID,
+	)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	count, err := exec.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	if count == 0 {
+		return fmt.Errorf("%s: %w", op, storage.ErrUserNotFound)
+	}
+
+	return nil
+}
+
+// GetByID returns the user with the counters computed on read, SQLite mode runs no event processor.
+func (s *UserStorage) GetByID(ctx context.Context, ID string) (*models.GetUserResponse, error) {
+	const op = "storage.sqlite.user.GetByID"
+
+	var user models.GetUserResponse
+
+	err := s.db.QueryRowContext(ctx,
+		`
+		SELECT
+			u.id, u.email, u.username, COALESCE(u.bio, ''), COALESCE(u.profile_pic, ''),
+			(SELECT COUNT(*) FROM "post" p WHERE p.user_id = u.id),
+			(SELECT COUNT(*) FROM "follow" f WHERE f.following_id = u.id),
+			(SELECT COUNT(*) FROM "follow" f WHERE f.follower_id = u.id)
+		FROM "users" u
+		WHERE u.id = $1`,
+		ID,
+	).Scan(
+		&user.ID,
+		&user.Email,
+		&user.Username,
+		&user.Bio,
+		&user.ProfilePic,
+		&user.PostsCount,
+		&user.FollowersCount,
+		&user.FollowingCount,
+	)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, storage.ErrUserNotFound
+		}
+
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return &user, nil
+}
+
+func (s *UserStorage) GetByEmail(ctx context.Context, email string) (*models.User, error) {
+	const op = "storage.sqlite.user.GetByEmail"
+
+	var user models.User
+
+	err := s.db.QueryRowContext(ctx,
+		`SELECT "id", "email", "username", "password" FROM "users" WHERE "email" = $1`,
+		email,
+	).Scan(&user.ID, &user.Email, &user.Username, &user.Password)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, fmt.Errorf("%s: %w", op, storage.ErrUserNotFound)
+		}
+
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return &user, nil
+}
+
+func (s *UserStorage) Update(ctx context.Context, updateUser models.UpdateUserRequest) error {
+	const op = "storage.sqlite.user.Update"
+
+	exec, err := s.db.ExecContext(ctx,
+		`UPDATE "users" SET "username" = $1, "email" = $2, "bio" = $3 WHERE "id" = $4`,
+		updateUser.Username,
+		updateUser.Email,
+		updateUser.Bio,
+		updateUser.ID,
+	)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	num, err := exec.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if num == 0 {
+		return fmt.Errorf("%s: %w", op, storage.ErrUserNotFound)
+	}
+
+	return nil
+}
+
+func (s *UserStorage) GetAllFollowers(ctx context.Context, userID int, after *models.Cursor, limit int) ([]models.GetAllFollowersResponse, *models.Cursor, error) {
+	const op = "storage.sqlite.user.GetAllFollowers"
+
+	followers, next, err := s.getFollowPage(ctx, `u.id = f.follower_id AND f.following_id = $1`, userID, after, limit)
+	if err != nil {
+		return nil, nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return followers, next, nil
+}
+
+func (s *UserStorage) GetAllFollowing(ctx context.Context, userID int, after *models.Cursor, limit int) ([]models.GetAllFollowersResponse, *models.Cursor, error) {
+	const op = "storage.sqlite.user.GetAllFollowing"
+
+	following, next, err := s.getFollowPage(ctx, `u.id = f.following_id AND f.follower_id = $1`, userID, after, limit)
+	if err != nil {
+		return nil, nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return following, next, nil
+}
+
+// getFollowPage lists users joined to the follow table by joinCondition, most recent follows first.
+// The cursor is the ID of the follow row.
+func (s *UserStorage) getFollowPage(ctx context.Context, joinCondition string, userID int, after *models.Cursor, limit int) ([]models.GetAllFollowersResponse, *models.Cursor, error) {
+	type followRow struct {
+		followID int
+		user     models.GetAllFollowersResponse
+	}
+
+	query := `
+		SELECT f.id, u.id, u.username, COALESCE(u.profile_pic, '')
+		FROM users u
+		JOIN follow AS f ON ` + joinCondition
+
+	var rows *sql.Rows
+	var err error
+
+	if after == nil {
+		rows, err = s.db.QueryContext(ctx, query+` ORDER BY f.id DESC LIMIT $2`, userID, limit+1)
+	} else {
+		rows, err = s.db.QueryContext(ctx, query+` AND f.id < $3 ORDER BY f.id DESC LIMIT $2`, userID, limit+1, after.ID)
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	defer rows.Close()
+
+	var page []followRow
+
+	for rows.Next() {
+		var row followRow
+
+		if err := rows.Scan(&row.followID, &row.user.ID, &row.user.Username, &row.user.ProfilePic); err != nil {
+			return nil, nil, err
+		}
+
+		page = append(page, row)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, nil, err
+	}
+
+	page, next := pagination.TrimPage(page, limit, func(row followRow) models.Cursor {
+		return models.Cursor{ID: row.followID}
+	})
+
+	users := make([]models.GetAllFollowersResponse, 0, len(page))
+	for _, row := range page {
+		users = append(users, row.user)
+	}
+
+	return users, next, nil
+}
@@ -5,29 +5,28 @@ import (
 	"database/sql"
 	"errors"
 	"fmt"
+	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/credentials"
 	"github.com/aws/aws-sdk-go-v2/service/s3"
 	internalConfig "kirkagram/internal/config"
+	"log/slog"
+	"strconv"
+	"strings"
+	"time"
 
 	_ "github.com/lib/pq"
 )
 
-type ConnectionInfo struct {
-	Host     string
-	Port     int
-	Username string
-	DBName   string
-	SSLMode  string
-	Password string
-}
-
 var (
 	ErrUserNotFound              = errors.New("User not found")
 	ErrEmailAlreadyRegistered    = errors.New("User with this email already exists")
 	ErrUsernameAlreadyRegistered = errors.New("User with this username already exists")
 	ErrUserAlreadyExists         = errors.New("User already exists")
 	ErrIncorrectPassword         = errors.New("Incorrect password")
+	ErrInvalidCredentials        = errors.New("Invalid email or password")
 	ErrNoSuchKey                 = errors.New("No such key")
+	ErrInvalidKey                = errors.New("Invalid key")
 	ErrPostExists                = errors.New("Post already exists")
 	ErrPostNotFound              = errors.New("Post not found")
 	ErrPostAlreadyLiked          = errors.New("Post already liked")
@@ -35,43 +34,122 @@ var (
 	SelfFollowError              = errors.New("Self follow error")
 	SelfUnFollowError            = errors.New("Self unfollow error")
 	ErrAlreadyFollowed           = errors.New("Already followed")
+	ErrCommentNotFound           = errors.New("Comment not found")
+	ErrCommentParentMismatch     = errors.New("Parent comment belongs to another post")
+	ErrRefreshTokenNotFound      = errors.New("Refresh token not found")
+	ErrRefreshTokenExpired       = errors.New("Refresh token expired")
+	ErrRefreshTokenReused        = errors.New("Refresh token reused")
+	ErrUnpublishable             = errors.New("Event can't be published")
 )
 
-func New(cfg *internalConfig.Config) *sql.DB {
-	// info := ConnectionInfo{
-	// 	Host:     "localhost",
-	// 	Port:     5432,
-	// 	Username: "myuser",
-	// 	DBName:   "mydatabase",
-	// 	SSLMode:  "disable",
-	// 	Password: "mypassword",
-	// }
+// maxConnectBackoff caps the pause between attempts to reach the database at startup.
+const maxConnectBackoff = 10 * time.Second
 
-	connStr := "host=localhost port=5467 user=user password=12345 dbname=kirkagram sslmode=disable"
+// New opens the connection pool and waits until the database accepts connections, retrying with
+// exponential backoff. It panics once the configured attempts are exhausted.
+func New(cfg *internalConfig.Config, log *slog.Logger) *sql.DB {
+	const op = "storage.New"
 
-	// db, err := sql.Open("postgres", fmt.Sprintf("host=%s port=%d user=%s password=%s dbname=%s sslmode=%s",
-	// 	info.Host, info.Port, info.Username, info.Password, info.DBName, info.SSLMode))
-
-	db, err := sql.Open("postgres", connStr)
+	db, err := sql.Open("postgres", dsn(cfg.Database))
 	if err != nil {
-		panic(err)
+		panic(fmt.Errorf("%s: %w", op, err))
 	}
 
-	if err := db.Ping(); err != nil {
-		fmt.Printf("failed to connect to database: %v\n", err)
-		panic(err)
+	db.SetMaxOpenConns(cfg.Database.MaxOpenConns)
+	db.SetMaxIdleConns(cfg.Database.MaxIdleConns)
+	db.SetConnMaxLifetime(cfg.Database.ConnMaxLifetime)
+	db.SetConnMaxIdleTime(cfg.Database.ConnMaxIdleTime)
+
+	log = log.With(
+		slog.String("op", op),
+		slog.String("host", cfg.Database.Host),
+		slog.Int("port", cfg.Database.Port),
+		slog.String("database", cfg.Database.Name),
+	)
+
+	backoff := cfg.Database.ConnectBackoff
+
+	for attempt := 1; ; attempt++ {
+		err := db.Ping()
+		if err == nil {
+			break
+		}
+
+		if attempt >= cfg.Database.ConnectAttempts {
+			db.Close()
+
+			panic(fmt.Errorf("%s: database is unreachable after %d attempts: %w", op, attempt, err))
+		}
+
+		log.Warn(
+			"Database is not ready, retrying",
+			slog.Int("attempt", attempt),
+			slog.Duration("backoff", backoff),
+			slog.String("error", err.Error()),
+		)
+
+		time.Sleep(backoff)
+		backoff = min(backoff*2, maxConnectBackoff)
 	}
 
+	log.Info("Connected to database")
+
 	return db
 }
 
-func NewS3Client() *s3.Client {
-	cfgS3, err := config.LoadDefaultConfig(context.Background())
+// dsn builds a lib/pq connection string. Values are quoted, so passwords may contain spaces and quotes.
+func dsn(cfg internalConfig.Database) string {
+	params := []struct {
+		key   string
+		value string
+	}{
+		{"host", cfg.Host},
+		{"port", strconv.Itoa(cfg.Port)},
+		{"user", cfg.User},
+		{"password", cfg.Password},
+		{"dbname", cfg.Name},
+		{"sslmode", cfg.SSLMode},
+		{"connect_timeout", strconv.Itoa(int(cfg.ConnectTimeout.Seconds()))},
+	}
+
+	quote := strings.NewReplacer(`\`, `\\`, `'`, `\'`)
+
+	parts := make([]string, 0, len(params))
+	for _, param := range params {
+		parts = append(parts, param.key+"='"+quote.Replace(param.value)+"'")
+	}
+
+	return strings.Join(parts, " ")
+}
+
+// NewS3Client builds the client from the photos.s3 settings. Region and credentials that aren't set
+// are taken from the environment and ~/.aws.
+func NewS3Client(cfg internalConfig.PhotosS3) *s3.Client {
+	var opts []func(*config.LoadOptions) error
+
+	if cfg.Region != "" {
+		opts = append(opts, config.WithRegion(cfg.Region))
+	}
+
+	if cfg.AccessKeyID != "" {
+		opts = append(opts, config.WithCredentialsProvider(
+			credentials.NewStaticCredentialsProvider(cfg.AccessKeyID, cfg.SecretAccessKey, ""),
+		))
+	}
+
+	cfgS3, err := config.LoadDefaultConfig(context.Background(), opts...)
 	if err != nil {
 		panic(err)
 	}
 
-	client := s3.NewFromConfig(cfgS3)
+	client := s3.NewFromConfig(cfgS3, func(o *s3.Options) {
+		if cfg.Endpoint != "" {
+			o.BaseEndpoint = aws.String(cfg.Endpoint)
+		}
+
+		// MinIO and other local servers usually don't resolve bucket subdomains.
+		o.UsePathStyle = cfg.UsePathStyle
+	})
 
 	return client
 }
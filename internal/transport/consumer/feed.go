@@ -0,0 +1,26 @@
+package consumer
+
+import (
+	"context"
+	k "kirkagram/internal/kafka"
+	"kirkagram/internal/models"
+)
+
+type Timeline interface {
+	PostCreated(ctx context.Context, post models.Posts) error
+	Followed(ctx context.Context, req models.FollowRequest) error
+	Unfollowed(ctx context.Context, req models.FollowRequest) error
+}
+
+// RegisterFeed subscribes the timeline to post and follow events so the materialized feeds stay up to date.
+func RegisterFeed(c k.Registry, timeline Timeline) {
+	k.HandleEvent(c, k.PostCreated, func(ctx context.Context, event *k.Event, post models.Posts) error {
+		return timeline.PostCreated(ctx, post)
+	})
+	k.HandleEvent(c, k.FollowCreated, func(ctx context.Context, event *k.Event, req models.FollowRequest) error {
+		return timeline.Followed(ctx, req)
+	})
+	k.HandleEvent(c, k.FollowDeleted, func(ctx context.Context, event *k.Event, req models.FollowRequest) error {
+		return timeline.Unfollowed(ctx, req)
+	})
+}
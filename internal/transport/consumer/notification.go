@@ -0,0 +1,45 @@
+package consumer
+
+import (
+	"context"
+	k "kirkagram/internal/kafka"
+	"kirkagram/internal/models"
+)
+
+type Notifier interface {
+	Liked(ctx context.Context, req models.LikeRequest) error
+	Unliked(ctx context.Context, req models.LikeRequest) error
+	Followed(ctx context.Context, req models.FollowRequest) error
+	Unfollowed(ctx context.Context, req models.FollowRequest) error
+	Commented(ctx context.Context, comment models.Comments) error
+}
+
+// RegisterNotifications subscribes the notifier to like, follow and comment events.
+func RegisterNotifications(c k.Registry, notifier Notifier) {
+	k.HandleEvent(c, k.LikeCreated, func(ctx context.Context, event *k.Event, req models.LikeRequest) error {
+		return notifier.Liked(ctx, req)
+	})
+	k.HandleEvent(c, k.LikeDeleted, func(ctx context.Context, event *k.Event, req models.LikeRequest) error {
+		return notifier.Unliked(ctx, req)
+	})
+	k.HandleEvent(c, k.FollowCreated, func(ctx context.Context, event *k.Event, req models.FollowRequest) error {
+		return notifier.Followed(ctx, req)
+	})
+	k.HandleEvent(c, k.FollowDeleted, func(ctx context.Context, event *k.Event, req models.FollowRequest) error {
+		return notifier.Unfollowed(ctx, req)
+	})
+	k.HandleEvent(c, k.CommentCreated, func(ctx context.Context, event *k.Event, comment models.Comments) error {
+		return notifier.Commented(ctx, comment)
+	})
+}
+
+type NotificationStream interface {
+	NotificationUpdated(event models.NotificationUpdatedEvent) error
+}
+
+// RegisterNotificationStream wakes the notification streams open in this process on notification changes.
+func RegisterNotificationStream(c k.Registry, stream NotificationStream) {
+	k.HandleEvent(c, k.NotificationUpdated, func(ctx context.Context, event *k.Event, updated models.NotificationUpdatedEvent) error {
+		return stream.NotificationUpdated(updated)
+	})
+}
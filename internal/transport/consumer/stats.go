@@ -0,0 +1,34 @@
+package consumer
+
+import (
+	"context"
+	k "kirkagram/internal/kafka"
+	"kirkagram/internal/models"
+)
+
+type Stats interface {
+	PostCreated(ctx context.Context, post models.Posts) error
+	PostDeleted(ctx context.Context, post models.Posts) error
+	Followed(ctx context.Context, req models.FollowRequest) error
+	Unfollowed(ctx context.Context, req models.FollowRequest) error
+	UserDeleted(ctx context.Context, event models.UserDeletedEvent) error
+}
+
+// RegisterStats subscribes the user counters to post and follow events.
+func RegisterStats(c k.Registry, stats Stats) {
+	k.HandleEvent(c, k.PostCreated, func(ctx context.Context, event *k.Event, post models.Posts) error {
+		return stats.PostCreated(ctx, post)
+	})
+	k.HandleEvent(c, k.PostDeleted, func(ctx context.Context, event *k.Event, post models.Posts) error {
+		return stats.PostDeleted(ctx, post)
+	})
+	k.HandleEvent(c, k.FollowCreated, func(ctx context.Context, event *k.Event, req models.FollowRequest) error {
+		return stats.Followed(ctx, req)
+	})
+	k.HandleEvent(c, k.FollowDeleted, func(ctx context.Context, event *k.Event, req models.FollowRequest) error {
+		return stats.Unfollowed(ctx, req)
+	})
+	k.HandleEvent(c, k.UserDeleted, func(ctx context.Context, event *k.Event, deleted models.UserDeletedEvent) error {
+		return stats.UserDeleted(ctx, deleted)
+	})
+}
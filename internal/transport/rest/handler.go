@@ -1,53 +1,87 @@
 package rest
 
 import (
+	"context"
+	"io"
 	"kirkagram/internal/models"
 	"kirkagram/internal/transport/rest/handlers"
+	"kirkagram/internal/transport/rest/middleware"
 	"log/slog"
 	"net/http"
+	"time"
 
 	"github.com/go-chi/chi"
+	chiMiddleware "github.com/go-chi/chi/middleware"
 )
 
 type Photo interface {
-	GetPhoto(key string) ([]byte, error)
-	UploadPhoto(key string, data []byte) error
+	GetPhoto(ctx context.Context, key string) (io.ReadCloser, int64, error)
+	UploadPhoto(ctx context.Context, key string, body io.Reader) error
 }
 
 type Post interface {
-	CreatePost(post models.CreatePostRequest) error
+	CreatePost(ctx context.Context, post models.CreatePostRequest) error
 }
 
 type Handler struct {
-	userHandler   *handlers.UserHandler
-	photoHandler  *handlers.PhotoHandler
-	postHandler   *handlers.PostHandler
-	likeHandler   *handlers.LikeHandler
-	followHandler *handlers.FollowHandler
-	log           *slog.Logger
+	authHandler         *handlers.AuthHandler
+	userHandler         *handlers.UserHandler
+	photoHandler        *handlers.PhotoHandler
+	postHandler         *handlers.PostHandler
+	likeHandler         *handlers.LikeHandler
+	followHandler       *handlers.FollowHandler
+	commentHandler      *handlers.CommentHandler
+	feedHandler         *handlers.FeedHandler
+	notificationHandler *handlers.NotificationHandler
+	authSecret          string
+	timeouts            Timeouts
+	log                 *slog.Logger
+}
+
+// Timeouts bound the time a route may take. Transfer is for the photo uploads and downloads,
+// which stream whole files and need much longer than the rest of the API.
+type Timeouts struct {
+	Request  time.Duration
+	Write    time.Duration
+	Transfer time.Duration
 }
 
 func NewHandler(
 	log *slog.Logger,
+	authSecret string,
+	timeouts Timeouts,
+	authHandler *handlers.AuthHandler,
 	userHandler *handlers.UserHandler,
 	photoHandler *handlers.PhotoHandler,
 	postHandler *handlers.PostHandler,
 	likeHandler *handlers.LikeHandler,
 	followHandler *handlers.FollowHandler,
+	commentHandler *handlers.CommentHandler,
+	feedHandler *handlers.FeedHandler,
+	notificationHandler *handlers.NotificationHandler,
 ) *Handler {
 	return &Handler{
-		userHandler:   userHandler,
-		photoHandler:  photoHandler,
-		postHandler:   postHandler,
-		likeHandler:   likeHandler,
-		followHandler: followHandler,
-		log:           log,
+		authHandler:         authHandler,
+		userHandler:         userHandler,
+		photoHandler:        photoHandler,
+		postHandler:         postHandler,
+		likeHandler:         likeHandler,
+		followHandler:       followHandler,
+		commentHandler:      commentHandler,
+		feedHandler:         feedHandler,
+		notificationHandler: notificationHandler,
+		authSecret:          authSecret,
+		timeouts:            timeouts,
+		log:                 log,
 	}
 }
 
+// InitRouter skips the routes of the handlers left nil, the SQLite backend has no comments, feed or notifications.
 func (h *Handler) InitRouter() *chi.Mux {
 	router := chi.NewRouter()
 
+	router.Use(middleware.Deadline(h.timeouts.Request, h.timeouts.Write))
+
 	router.Get("/", func(w http.ResponseWriter, r *http.Request) {
 		h.log.Info("Hello")
 		w.Write([]byte("Hello"))
@@ -56,28 +90,83 @@ func (h *Handler) InitRouter() *chi.Mux {
 	router.Route("/api", func(r chi.Router) {
 		h.log.Info("Init api routes")
 
-		r.Post("/user", h.userHandler.Register)
-		r.Get("/user/{id}", h.userHandler.GetUser)
-		r.Put("/user", h.userHandler.UpdateUser)
-		r.Get("/user/{userID}/followers", h.userHandler.GetAllFollowers)
-		r.Get("/user/{userID}/following", h.userHandler.GetAllFollowing)
-		r.Delete("/user/{Id}", h.userHandler.DeleteUser)
+		// The stream is long-lived, its deadlines are cleared and it sets write deadlines itself.
+		if h.notificationHandler != nil {
+			r.With(middleware.Deadline(0, 0), middleware.StreamAuth(h.authSecret, h.log)).Get("/notifications/stream", h.notificationHandler.Stream)
+		}
+
+		// Photos are streamed in and out, they get the transfer timeout instead of the request one.
+		r.Group(func(r chi.Router) {
+			r.Use(middleware.Deadline(h.timeouts.Transfer, h.timeouts.Transfer))
+			r.Use(chiMiddleware.Timeout(h.timeouts.Transfer))
+
+			r.Get("/photo/{key}", h.photoHandler.GetPhotoURL)
+
+			r.Group(func(r chi.Router) {
+				r.Use(middleware.Auth(h.authSecret, h.log))
+
+				r.Post("/photo", h.photoHandler.UploadPhoto)
+				r.Post("/post", h.postHandler.CreatePost)
+			})
+		})
+
+		r.Group(func(r chi.Router) {
+			// The deadline is put into the request context, so queries and S3 transfers of a slow request are canceled.
+			r.Use(chiMiddleware.Timeout(h.timeouts.Request))
+
+			r.Post("/login", h.authHandler.Login)
+			r.Post("/refresh", h.authHandler.Refresh)
+			r.Post("/logout", h.authHandler.Logout)
+
+			r.Post("/user", h.userHandler.Register)
+			r.Get("/user/{id}", h.userHandler.GetUser)
+			r.Get("/user/{userID}/followers", h.userHandler.GetAllFollowers)
+			r.Get("/user/{userID}/following", h.userHandler.GetAllFollowing)
+
+			r.Get("/post/all", h.postHandler.GetAllPosts)
+			r.Get("/post/{id}", h.postHandler.GetPostByID)
+			r.Get("/post/user/{userId}", h.postHandler.GetUserPosts)
+
+			if h.commentHandler != nil {
+				r.Get("/post/{postID}/comments", h.commentHandler.GetComments)
+				r.Get("/comment/{id}/replies", h.commentHandler.GetReplies)
+			}
+
+			r.Get("/like/{postID}", h.likeHandler.GetLikes)
+
+			r.Group(func(r chi.Router) {
+				r.Use(middleware.Auth(h.authSecret, h.log))
+
+				r.Post("/logout/all", h.authHandler.LogoutAll)
+
+				if h.feedHandler != nil {
+					r.Get("/feed", h.feedHandler.GetFeed)
+				}
+
+				if h.notificationHandler != nil {
+					r.Get("/notifications", h.notificationHandler.GetNotifications)
+					r.Get("/notifications/unread-count", h.notificationHandler.GetUnreadCount)
+					r.Post("/notifications/read", h.notificationHandler.MarkRead)
+				}
+
+				r.Put("/user", h.userHandler.UpdateUser)
+				r.Delete("/user/{Id}", h.userHandler.DeleteUser)
 
-		r.Get("/photo/{key}", h.photoHandler.GetPhotoURL)
-		r.Post("/photo", h.photoHandler.UploadPhoto)
+				r.Delete("/post/{id}", h.postHandler.DeletePost)
 
-		r.Post("/post", h.postHandler.CreatePost)
-		r.Get("/post/all", h.postHandler.GetAllPosts)
-		r.Get("/post/{id}", h.postHandler.GetPostByID)
-		r.Get("/post/user/{userId}", h.postHandler.GetUserPosts)
-		r.Delete("/post/{userId}", h.postHandler.DeletePost)
+				if h.commentHandler != nil {
+					r.Post("/post/{postID}/comments", h.commentHandler.CreateComment)
+					r.Put("/comment/{id}", h.commentHandler.UpdateComment)
+					r.Delete("/comment/{id}", h.commentHandler.DeleteComment)
+				}
 
-		r.Post("/like", h.likeHandler.LikePost)
-		r.Delete("/like", h.likeHandler.UnlikePost)
-		r.Get("/like/{postID}", h.likeHandler.GetLikes)
+				r.Post("/like", h.likeHandler.LikePost)
+				r.Delete("/like", h.likeHandler.UnlikePost)
 
-		r.Post("/follow", h.followHandler.Follow)
-		r.Delete("/unfollow", h.followHandler.UnFollow)
+				r.Post("/follow", h.followHandler.Follow)
+				r.Delete("/unfollow", h.followHandler.UnFollow)
+			})
+		})
 	})
 
 	return router
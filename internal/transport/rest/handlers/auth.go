@@ -0,0 +1,221 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"github.com/go-chi/render"
+	"github.com/go-playground/validator"
+	"kirkagram/internal/lib/logger/handlers/customResponse"
+	"kirkagram/internal/models"
+	"kirkagram/internal/storage"
+	"kirkagram/internal/transport/rest/middleware"
+	"log/slog"
+	"net/http"
+)
+
+type Auth interface {
+	Login(ctx context.Context, req models.LoginRequest) (*models.TokenPair, error)
+	Refresh(ctx context.Context, req models.RefreshRequest) (*models.TokenPair, error)
+	Logout(ctx context.Context, req models.RefreshRequest) error
+	LogoutAll(ctx context.Context, userID int) error
+}
+
+type AuthHandler struct {
+	authService Auth
+	log         *slog.Logger
+}
+
+func NewAuthHandler(authService Auth, log *slog.Logger) *AuthHandler {
+	return &AuthHandler{
+		authService: authService,
+		log:         log,
+	}
+}
+
+// Login godoc
+// @Summary Log in
+// @Description Exchange email and password for an access and refresh token pair
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param credentials body models.LoginRequest true "User credentials"
+// @Success 200 {object} models.TokenPair
+// @Failure 400 {object} customResponse.Error
+// @Failure 401 {object} customResponse.Error
+// @Failure 500 {object} customResponse.Error
+// @Router /login [post]
+func (a *AuthHandler) Login(w http.ResponseWriter, r *http.Request) {
+	const op = "rest.handlers.auth.Login"
+
+	log := a.log.With(slog.String("op", op))
+	log.Info("start login")
+
+	var req models.LoginRequest
+	err := render.DecodeJSON(r.Body, &req)
+	if err != nil {
+		log.Error("decode json error", slog.String("error", err.Error()))
+
+		render.Status(r, http.StatusBadRequest)
+		render.JSON(w, r, customResponse.NewError(err.Error()))
+
+		return
+	}
+
+	tokens, err := a.authService.Login(r.Context(), req)
+	if err != nil {
+		log.Info("login error", slog.String("email", req.Email), slog.String("error", err.Error()))
+
+		renderAuthError(w, r, err)
+
+		return
+	}
+
+	log.Info("login completed", slog.String("email", req.Email))
+
+	render.Status(r, http.StatusOK)
+	render.JSON(w, r, tokens)
+}
+
+// Refresh godoc
+// @Summary Refresh tokens
+// @Description Exchange a refresh token for a new token pair. The old refresh token is revoked.
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param request body models.RefreshRequest true "Refresh token"
+// @Success 200 {object} models.TokenPair
+// @Failure 400 {object} customResponse.Error
+// @Failure 401 {object} customResponse.Error
+// @Failure 500 {object} customResponse.Error
+// @Router /refresh [post]
+func (a *AuthHandler) Refresh(w http.ResponseWriter, r *http.Request) {
+	const op = "rest.handlers.auth.Refresh"
+
+	log := a.log.With(slog.String("op", op))
+	log.Info("start refresh")
+
+	var req models.RefreshRequest
+	err := render.DecodeJSON(r.Body, &req)
+	if err != nil {
+		log.Error("decode json error", slog.String("error", err.Error()))
+
+		render.Status(r, http.StatusBadRequest)
+		render.JSON(w, r, customResponse.NewError(err.Error()))
+
+		return
+	}
+
+	tokens, err := a.authService.Refresh(r.Context(), req)
+	if err != nil {
+		log.Info("refresh error", slog.String("error", err.Error()))
+
+		renderAuthError(w, r, err)
+
+		return
+	}
+
+	render.Status(r, http.StatusOK)
+	render.JSON(w, r, tokens)
+}
+
+// Logout godoc
+// @Summary Log out
+// @Description Revoke the refresh token and every token rotated from the same login
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param request body models.RefreshRequest true "Refresh token"
+// @Success 200 {object} customResponse.CustomStatus
+// @Failure 400 {object} customResponse.Error
+// @Failure 401 {object} customResponse.Error
+// @Failure 500 {object} customResponse.Error
+// @Router /logout [post]
+func (a *AuthHandler) Logout(w http.ResponseWriter, r *http.Request) {
+	const op = "rest.handlers.auth.Logout"
+
+	log := a.log.With(slog.String("op", op))
+	log.Info("start logout")
+
+	var req models.RefreshRequest
+	err := render.DecodeJSON(r.Body, &req)
+	if err != nil {
+		log.Error("decode json error", slog.String("error", err.Error()))
+
+		render.Status(r, http.StatusBadRequest)
+		render.JSON(w, r, customResponse.NewError(err.Error()))
+
+		return
+	}
+
+	err = a.authService.Logout(r.Context(), req)
+	if err != nil {
+		log.Info("logout error", slog.String("error", err.Error()))
+
+		renderAuthError(w, r, err)
+
+		return
+	}
+
+	render.Status(r, http.StatusOK)
+	render.JSON(w, r, customResponse.NewStatus(200))
+}
+
+// LogoutAll godoc
+// @Summary Log out of all devices
+// @Description Revoke every refresh token of the authenticated user
+// @Tags auth
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} customResponse.CustomStatus
+// @Failure 401 {object} customResponse.Error
+// @Failure 500 {object} customResponse.Error
+// @Router /logout/all [post]
+func (a *AuthHandler) LogoutAll(w http.ResponseWriter, r *http.Request) {
+	const op = "rest.handlers.auth.LogoutAll"
+
+	log := a.log.With(slog.String("op", op))
+	log.Info("start logout from all devices")
+
+	userID, ok := middleware.UserIDFromContext(r.Context())
+	if !ok {
+		log.Error("user id is missing in context")
+
+		render.Status(r, http.StatusUnauthorized)
+		render.JSON(w, r, customResponse.NewError("unauthorized"))
+
+		return
+	}
+
+	err := a.authService.LogoutAll(r.Context(), userID)
+	if err != nil {
+		log.Error("logout all error", slog.Int("userID", userID), slog.String("error", err.Error()))
+
+		renderAuthError(w, r, err)
+
+		return
+	}
+
+	render.Status(r, http.StatusOK)
+	render.JSON(w, r, customResponse.NewStatus(200))
+}
+
+func renderAuthError(w http.ResponseWriter, r *http.Request, err error) {
+	var validateErr validator.ValidationErrors
+
+	switch {
+	case errors.As(err, &validateErr):
+		render.Status(r, http.StatusBadRequest)
+		render.JSON(w, r, customResponse.NewError(validateErr.Error()))
+	case errors.Is(err, storage.ErrInvalidCredentials):
+		render.Status(r, http.StatusUnauthorized)
+		render.JSON(w, r, customResponse.NewError(storage.ErrInvalidCredentials.Error()))
+	case errors.Is(err, storage.ErrRefreshTokenNotFound),
+		errors.Is(err, storage.ErrRefreshTokenExpired),
+		errors.Is(err, storage.ErrRefreshTokenReused):
+		render.Status(r, http.StatusUnauthorized)
+		render.JSON(w, r, customResponse.NewError("invalid refresh token"))
+	default:
+		render.Status(r, http.StatusInternalServerError)
+		render.JSON(w, r, customResponse.NewError(err.Error()))
+	}
+}
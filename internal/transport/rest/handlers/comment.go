@@ -0,0 +1,360 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"github.com/go-chi/chi"
+	"github.com/go-chi/render"
+	"github.com/go-playground/validator"
+	"kirkagram/internal/lib/logger/handlers/customResponse"
+	"kirkagram/internal/lib/pagination"
+	"kirkagram/internal/models"
+	"kirkagram/internal/service"
+	"kirkagram/internal/storage"
+	"kirkagram/internal/transport/rest/middleware"
+	"log/slog"
+	"net/http"
+	"strconv"
+)
+
+type Comment interface {
+	CreateComment(ctx context.Context, callerID int, req models.CreateCommentRequest) (*models.Comments, error)
+	GetCommentsByPostID(ctx context.Context, postID int, cursor string, limit int) (*models.Page[models.Comments], error)
+	GetReplies(ctx context.Context, commentID int, cursor string, limit int) (*models.Page[models.Comments], error)
+	UpdateComment(ctx context.Context, callerID int, ID int, req models.UpdateCommentRequest) error
+	DeleteComment(ctx context.Context, callerID int, ID int) error
+}
+
+type CommentHandler struct {
+	commentService Comment
+	log            *slog.Logger
+}
+
+func NewCommentHandler(commentService Comment, log *slog.Logger) *CommentHandler {
+	return &CommentHandler{
+		commentService: commentService,
+		log:            log,
+	}
+}
+
+// CreateComment godoc
+// @Summary Comment a post
+// @Description Leave a comment under a post on behalf of the authenticated user. Set parent_id to reply to a comment
+// @Tags comments
+// @Accept json
+// @Produce json
+// @Param postID path int true "Post ID"
+// @Param request body models.CreateCommentRequest true "Comment content and optional parent comment ID"
+// @Security BearerAuth
+// @Success 201 {object} models.Comments
+// @Failure 400 {object} customResponse.Error
+// @Failure 401 {object} customResponse.Error
+// @Failure 404 {object} customResponse.Error
+// @Failure 500 {object} customResponse.Error
+// @Router /post/{postID}/comments [post]
+func (c *CommentHandler) CreateComment(w http.ResponseWriter, r *http.Request) {
+	const op = "rest.handlers.comment.CreateComment"
+
+	log := c.log.With(slog.String("op", op))
+	log.Info("starting create comment")
+
+	userID, ok := middleware.UserIDFromContext(r.Context())
+	if !ok {
+		log.Error("user id is missing in context")
+
+		render.Status(r, http.StatusUnauthorized)
+		render.JSON(w, r, customResponse.NewError("unauthorized"))
+
+		return
+	}
+
+	postID, err := strconv.Atoi(chi.URLParam(r, "postID"))
+	if err != nil {
+		log.Error("invalid postID", slog.String("error", err.Error()))
+
+		render.Status(r, http.StatusBadRequest)
+		render.JSON(w, r, customResponse.NewError("postID must be numeric"))
+
+		return
+	}
+
+	var req models.CreateCommentRequest
+	err = render.DecodeJSON(r.Body, &req)
+	if err != nil {
+		log.Error("unable to decode body", slog.String("error", err.Error()))
+
+		render.Status(r, http.StatusBadRequest)
+		render.JSON(w, r, customResponse.NewError(err.Error()))
+
+		return
+	}
+
+	req.PostID = postID
+
+	comment, err := c.commentService.CreateComment(r.Context(), userID, req)
+	if err != nil {
+		log.Error("unable to create comment", slog.Int("postID", postID), slog.String("error", err.Error()))
+
+		renderCommentError(w, r, err)
+
+		return
+	}
+
+	log.Info("comment created", slog.Int("commentID", comment.ID))
+
+	render.Status(r, http.StatusCreated)
+	render.JSON(w, r, comment)
+}
+
+// GetComments godoc
+// @Summary Get comments of a post
+// @Description Get top-level comments of a post with their reply counts, newest first
+// @Tags comments
+// @Accept json
+// @Produce json
+// @Param postID path int true "Post ID"
+// @Param cursor query string false "next_cursor from the previous page"
+// @Param limit query int false "Page size, 20 by default and 100 at most"
+// @Success 200 {object} models.Page[models.Comments]
+// @Failure 400 {object} customResponse.Error
+// @Failure 500 {object} customResponse.Error
+// @Router /post/{postID}/comments [get]
+func (c *CommentHandler) GetComments(w http.ResponseWriter, r *http.Request) {
+	const op = "rest.handlers.comment.GetComments"
+
+	log := c.log.With(slog.String("op", op))
+	log.Info("starting get comments")
+
+	postID, err := strconv.Atoi(chi.URLParam(r, "postID"))
+	if err != nil {
+		log.Error("invalid postID", slog.String("error", err.Error()))
+
+		render.Status(r, http.StatusBadRequest)
+		render.JSON(w, r, customResponse.NewError("postID must be numeric"))
+
+		return
+	}
+
+	cursor, limit, err := pageParams(r)
+	if err != nil {
+		log.Error("invalid limit", slog.String("error", err.Error()))
+
+		render.Status(r, http.StatusBadRequest)
+		render.JSON(w, r, customResponse.NewError("limit must be numeric"))
+
+		return
+	}
+
+	comments, err := c.commentService.GetCommentsByPostID(r.Context(), postID, cursor, limit)
+	if err != nil {
+		log.Error("unable to get comments", slog.Int("postID", postID), slog.String("error", err.Error()))
+
+		renderCommentError(w, r, err)
+
+		return
+	}
+
+	render.Status(r, http.StatusOK)
+	render.JSON(w, r, comments)
+}
+
+// GetReplies godoc
+// @Summary Get replies to a comment
+// @Description Get replies to a top-level comment, oldest first
+// @Tags comments
+// @Accept json
+// @Produce json
+// @Param id path int true "Comment ID"
+// @Param cursor query string false "next_cursor from the previous page"
+// @Param limit query int false "Page size, 20 by default and 100 at most"
+// @Success 200 {object} models.Page[models.Comments]
+// @Failure 400 {object} customResponse.Error
+// @Failure 404 {object} customResponse.Error
+// @Failure 500 {object} customResponse.Error
+// @Router /comment/{id}/replies [get]
+func (c *CommentHandler) GetReplies(w http.ResponseWriter, r *http.Request) {
+	const op = "rest.handlers.comment.GetReplies"
+
+	log := c.log.With(slog.String("op", op))
+	log.Info("starting get replies")
+
+	ID, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		log.Error("invalid id", slog.String("error", err.Error()))
+
+		render.Status(r, http.StatusBadRequest)
+		render.JSON(w, r, customResponse.NewError("id must be numeric"))
+
+		return
+	}
+
+	cursor, limit, err := pageParams(r)
+	if err != nil {
+		log.Error("invalid limit", slog.String("error", err.Error()))
+
+		render.Status(r, http.StatusBadRequest)
+		render.JSON(w, r, customResponse.NewError("limit must be numeric"))
+
+		return
+	}
+
+	replies, err := c.commentService.GetReplies(r.Context(), ID, cursor, limit)
+	if err != nil {
+		log.Error("unable to get replies", slog.Int("id", ID), slog.String("error", err.Error()))
+
+		renderCommentError(w, r, err)
+
+		return
+	}
+
+	render.Status(r, http.StatusOK)
+	render.JSON(w, r, replies)
+}
+
+// UpdateComment godoc
+// @Summary Edit a comment
+// @Description Edit a comment. Only the author of the comment can edit it
+// @Tags comments
+// @Accept json
+// @Produce json
+// @Param id path int true "Comment ID"
+// @Param request body models.UpdateCommentRequest true "New comment content"
+// @Security BearerAuth
+// @Success 200 {object} customResponse.CustomStatus
+// @Failure 400 {object} customResponse.Error
+// @Failure 401 {object} customResponse.Error
+// @Failure 403 {object} customResponse.Error
+// @Failure 404 {object} customResponse.Error
+// @Failure 500 {object} customResponse.Error
+// @Router /comment/{id} [put]
+func (c *CommentHandler) UpdateComment(w http.ResponseWriter, r *http.Request) {
+	const op = "rest.handlers.comment.UpdateComment"
+
+	log := c.log.With(slog.String("op", op))
+	log.Info("starting update comment")
+
+	userID, ok := middleware.UserIDFromContext(r.Context())
+	if !ok {
+		log.Error("user id is missing in context")
+
+		render.Status(r, http.StatusUnauthorized)
+		render.JSON(w, r, customResponse.NewError("unauthorized"))
+
+		return
+	}
+
+	ID, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		log.Error("invalid id", slog.String("error", err.Error()))
+
+		render.Status(r, http.StatusBadRequest)
+		render.JSON(w, r, customResponse.NewError("id must be numeric"))
+
+		return
+	}
+
+	var req models.UpdateCommentRequest
+	err = render.DecodeJSON(r.Body, &req)
+	if err != nil {
+		log.Error("unable to decode body", slog.String("error", err.Error()))
+
+		render.Status(r, http.StatusBadRequest)
+		render.JSON(w, r, customResponse.NewError(err.Error()))
+
+		return
+	}
+
+	err = c.commentService.UpdateComment(r.Context(), userID, ID, req)
+	if err != nil {
+		log.Error("unable to update comment", slog.Int("id", ID), slog.String("error", err.Error()))
+
+		renderCommentError(w, r, err)
+
+		return
+	}
+
+	render.Status(r, http.StatusOK)
+	render.JSON(w, r, customResponse.NewStatus(200))
+}
+
+// DeleteComment godoc
+// @Summary Delete a comment
+// @Description Delete a comment. The author of the comment and the author of the post can delete it
+// @Tags comments
+// @Accept json
+// @Produce json
+// @Param id path int true "Comment ID"
+// @Security BearerAuth
+// @Success 200 {object} customResponse.CustomStatus
+// @Failure 400 {object} customResponse.Error
+// @Failure 401 {object} customResponse.Error
+// @Failure 403 {object} customResponse.Error
+// @Failure 404 {object} customResponse.Error
+// @Failure 500 {object} customResponse.Error
+// @Router /comment/{id} [delete]
+func (c *CommentHandler) DeleteComment(w http.ResponseWriter, r *http.Request) {
+	const op = "rest.handlers.comment.DeleteComment"
+
+	log := c.log.With(slog.String("op", op))
+	log.Info("starting delete comment")
+
+	userID, ok := middleware.UserIDFromContext(r.Context())
+	if !ok {
+		log.Error("user id is missing in context")
+
+		render.Status(r, http.StatusUnauthorized)
+		render.JSON(w, r, customResponse.NewError("unauthorized"))
+
+		return
+	}
+
+	ID, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		log.Error("invalid id", slog.String("error", err.Error()))
+
+		render.Status(r, http.StatusBadRequest)
+		render.JSON(w, r, customResponse.NewError("id must be numeric"))
+
+		return
+	}
+
+	err = c.commentService.DeleteComment(r.Context(), userID, ID)
+	if err != nil {
+		log.Error("unable to delete comment", slog.Int("id", ID), slog.String("error", err.Error()))
+
+		renderCommentError(w, r, err)
+
+		return
+	}
+
+	render.Status(r, http.StatusOK)
+	render.JSON(w, r, customResponse.NewStatus(200))
+}
+
+func renderCommentError(w http.ResponseWriter, r *http.Request, err error) {
+	var validateErr validator.ValidationErrors
+
+	switch {
+	case errors.As(err, &validateErr):
+		render.Status(r, http.StatusBadRequest)
+		render.JSON(w, r, customResponse.NewError(validateErr.Error()))
+	case errors.Is(err, pagination.ErrInvalidCursor):
+		render.Status(r, http.StatusBadRequest)
+		render.JSON(w, r, customResponse.NewError(pagination.ErrInvalidCursor.Error()))
+	case errors.Is(err, service.ErrForbidden):
+		render.Status(r, http.StatusForbidden)
+		render.JSON(w, r, customResponse.NewError(service.ErrForbidden.Error()))
+	case errors.Is(err, storage.ErrCommentNotFound):
+		render.Status(r, http.StatusNotFound)
+		render.JSON(w, r, customResponse.NewError(storage.ErrCommentNotFound.Error()))
+	case errors.Is(err, storage.ErrCommentParentMismatch):
+		render.Status(r, http.StatusBadRequest)
+		render.JSON(w, r, customResponse.NewError(storage.ErrCommentParentMismatch.Error()))
+	case errors.Is(err, storage.ErrPostNotFound):
+		render.Status(r, http.StatusNotFound)
+		render.JSON(w, r, customResponse.NewError(storage.ErrPostNotFound.Error()))
+	default:
+		render.Status(r, http.StatusInternalServerError)
+		render.JSON(w, r, customResponse.NewError(err.Error()))
+	}
+}
@@ -0,0 +1,92 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"github.com/go-chi/render"
+	"kirkagram/internal/lib/logger/handlers/customResponse"
+	"kirkagram/internal/lib/pagination"
+	"kirkagram/internal/models"
+	"kirkagram/internal/transport/rest/middleware"
+	"log/slog"
+	"net/http"
+)
+
+type Feed interface {
+	GetFeed(ctx context.Context, userID int, cursor string, limit int) (*models.Page[models.FeedItem], error)
+}
+
+type FeedHandler struct {
+	feedService Feed
+	log         *slog.Logger
+}
+
+func NewFeedHandler(feedService Feed, log *slog.Logger) *FeedHandler {
+	return &FeedHandler{
+		feedService: feedService,
+		log:         log,
+	}
+}
+
+// GetFeed godoc
+// @Summary Get home feed
+// @Description Get posts of the accounts the authenticated user follows, newest first
+// @Tags feed
+// @Accept json
+// @Produce json
+// @Param cursor query string false "next_cursor from the previous page"
+// @Param limit query int false "Page size, 20 by default and 100 at most"
+// @Security BearerAuth
+// @Success 200 {object} models.Page[models.FeedItem]
+// @Failure 400 {object} customResponse.Error
+// @Failure 401 {object} customResponse.Error
+// @Failure 500 {object} customResponse.Error
+// @Router /feed [get]
+func (f *FeedHandler) GetFeed(w http.ResponseWriter, r *http.Request) {
+	const op = "rest.handlers.feed.GetFeed"
+
+	log := f.log.With(slog.String("op", op))
+	log.Info("starting get feed")
+
+	userID, ok := middleware.UserIDFromContext(r.Context())
+	if !ok {
+		log.Error("user id is missing in context")
+
+		render.Status(r, http.StatusUnauthorized)
+		render.JSON(w, r, customResponse.NewError("unauthorized"))
+
+		return
+	}
+
+	cursor, limit, err := pageParams(r)
+	if err != nil {
+		log.Error("invalid limit", slog.String("error", err.Error()))
+
+		render.Status(r, http.StatusBadRequest)
+		render.JSON(w, r, customResponse.NewError("limit must be numeric"))
+
+		return
+	}
+
+	page, err := f.feedService.GetFeed(r.Context(), userID, cursor, limit)
+	if err != nil {
+		if errors.Is(err, pagination.ErrInvalidCursor) {
+			log.Error("invalid cursor", slog.String("error", err.Error()))
+
+			render.Status(r, http.StatusBadRequest)
+			render.JSON(w, r, customResponse.NewError(pagination.ErrInvalidCursor.Error()))
+
+			return
+		}
+
+		log.Error("unable to get feed", slog.Int("userID", userID), slog.String("error", err.Error()))
+
+		render.Status(r, http.StatusInternalServerError)
+		render.JSON(w, r, customResponse.NewError(err.Error()))
+
+		return
+	}
+
+	render.Status(r, http.StatusOK)
+	render.JSON(w, r, page)
+}
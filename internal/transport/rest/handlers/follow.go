@@ -1,17 +1,20 @@
 package handlers
 
 import (
+	"context"
 	"errors"
 	"github.com/go-chi/render"
 	"kirkagram/internal/lib/logger/handlers/customResponse"
 	"kirkagram/internal/models"
+	"kirkagram/internal/service"
+	"kirkagram/internal/transport/rest/middleware"
 	"log/slog"
 	"net/http"
 )
 
 type Follow interface {
-	FollowByID(req models.FollowRequest) error
-	UnFollowByID(req models.FollowRequest) error
+	FollowByID(ctx context.Context, callerID int, req models.FollowRequest) error
+	UnFollowByID(ctx context.Context, callerID int, req models.FollowRequest) error
 }
 
 type FollowHandler struct {
@@ -33,8 +36,11 @@ func NewFollowHandler(followService Follow, log *slog.Logger) *FollowHandler {
 // @Accept json
 // @Produce json
 // @Param request body models.FollowRequest true "Follow request"
+// @Security BearerAuth
 // @Success 201 {object} customResponse.CustomStatus
 // @Failure 400 {object} customResponse.Error
+// @Failure 401 {object} customResponse.Error
+// @Failure 403 {object} customResponse.Error
 // @Failure 500 {object} customResponse.Error
 // @Router /follow [post]
 func (f *FollowHandler) Follow(w http.ResponseWriter, r *http.Request) {
@@ -43,6 +49,16 @@ func (f *FollowHandler) Follow(w http.ResponseWriter, r *http.Request) {
 	log := f.log.With(slog.String("op", op))
 	log.Info("starting delete post")
 
+	userID, ok := middleware.UserIDFromContext(r.Context())
+	if !ok {
+		log.Error("user id is missing in context")
+
+		render.Status(r, http.StatusUnauthorized)
+		render.JSON(w, r, customResponse.NewError("unauthorized"))
+
+		return
+	}
+
 	var req models.FollowRequest
 
 	err := render.DecodeJSON(r.Body, &req)
@@ -55,8 +71,17 @@ func (f *FollowHandler) Follow(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	err = f.followService.FollowByID(req)
+	err = f.followService.FollowByID(r.Context(), userID, req)
 	if err != nil {
+		if errors.Is(err, service.ErrForbidden) {
+			log.Error("user can follow only on own behalf", slog.Int("callerID", userID))
+
+			render.Status(r, http.StatusForbidden)
+			render.JSON(w, r, customResponse.NewError(service.ErrForbidden.Error()))
+
+			return
+		}
+
 		log.Error("error following post", slog.String("error", err.Error()))
 
 		render.Status(r, http.StatusInternalServerError)
@@ -77,8 +102,11 @@ func (f *FollowHandler) Follow(w http.ResponseWriter, r *http.Request) {
 // @Accept json
 // @Produce json
 // @Param request body models.FollowRequest true "Unfollow request"
+// @Security BearerAuth
 // @Success 201 {object} customResponse.CustomStatus
 // @Failure 400 {object} customResponse.Error
+// @Failure 401 {object} customResponse.Error
+// @Failure 403 {object} customResponse.Error
 // @Failure 500 {object} customResponse.Error
 // @Router /unfollow [delete]
 func (f *FollowHandler) UnFollow(w http.ResponseWriter, r *http.Request) {
@@ -87,6 +115,16 @@ func (f *FollowHandler) UnFollow(w http.ResponseWriter, r *http.Request) {
 	log := f.log.With(slog.String("op", op))
 	log.Info("starting delete post")
 
+	userID, ok := middleware.UserIDFromContext(r.Context())
+	if !ok {
+		log.Error("user id is missing in context")
+
+		render.Status(r, http.StatusUnauthorized)
+		render.JSON(w, r, customResponse.NewError("unauthorized"))
+
+		return
+	}
+
 	var req models.FollowRequest
 
 	err := render.DecodeJSON(r.Body, &req)
@@ -99,8 +137,17 @@ func (f *FollowHandler) UnFollow(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	err = f.followService.UnFollowByID(req)
+	err = f.followService.UnFollowByID(r.Context(), userID, req)
 	if err != nil {
+		if errors.Is(err, service.ErrForbidden) {
+			log.Error("user can unfollow only on own behalf", slog.Int("callerID", userID))
+
+			render.Status(r, http.StatusForbidden)
+			render.JSON(w, r, customResponse.NewError(service.ErrForbidden.Error()))
+
+			return
+		}
+
 		log.Error("error following post", slog.String("error", err.Error()))
 
 		render.Status(r, http.StatusInternalServerError)
@@ -1,20 +1,22 @@
 package handlers
 
 import (
+	"context"
 	"errors"
 	"github.com/go-chi/chi"
 	"github.com/go-chi/render"
 	"kirkagram/internal/lib/logger/handlers/customResponse"
 	"kirkagram/internal/models"
+	"kirkagram/internal/transport/rest/middleware"
 	"log/slog"
 	"net/http"
 	"strconv"
 )
 
 type Like interface {
-	LikePostByID(likeReq *models.LikeRequest) error
-	UnlikePostByID(likeReq *models.LikeRequest) error
-	GetLikesByID(postID int) (models.LikeResponse, error)
+	LikePostByID(ctx context.Context, likeReq *models.LikeRequest) error
+	UnlikePostByID(ctx context.Context, likeReq *models.LikeRequest) error
+	GetLikesByID(ctx context.Context, postID int) (models.LikeResponse, error)
 }
 
 type LikeHandler struct {
@@ -66,7 +68,7 @@ func (l *LikeHandler) GetLikes(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	count, err := l.likeService.GetLikesByID(postIDInt)
+	count, err := l.likeService.GetLikesByID(r.Context(), postIDInt)
 	if err != nil {
 		log.Error("error getting likes by postID", slog.String("postID", postID), slog.String("error", err.Error()))
 
@@ -88,6 +90,7 @@ func (l *LikeHandler) GetLikes(w http.ResponseWriter, r *http.Request) {
 // @Accept json
 // @Produce json
 // @Param request body models.LikeRequest true "Unlike request"
+// @Security BearerAuth
 // @Success 200 {object} customResponse.CustomStatus
 // @Failure 400 {object} customResponse.Error
 // @Failure 500 {object} customResponse.Error
@@ -98,6 +101,16 @@ func (l *LikeHandler) UnlikePost(w http.ResponseWriter, r *http.Request) {
 	log := l.log.With(slog.String("op", op))
 	log.Info("starting delete post")
 
+	userID, ok := middleware.UserIDFromContext(r.Context())
+	if !ok {
+		log.Error("user id is missing in context")
+
+		render.Status(r, http.StatusUnauthorized)
+		render.JSON(w, r, customResponse.NewError("unauthorized"))
+
+		return
+	}
+
 	var req models.LikeRequest
 	err := render.DecodeJSON(r.Body, &req)
 	if err != nil {
@@ -110,7 +123,9 @@ func (l *LikeHandler) UnlikePost(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	err = l.likeService.UnlikePostByID(&req)
+	req.UserID = userID
+
+	err = l.likeService.UnlikePostByID(r.Context(), &req)
 	if err != nil {
 		log.Error("unable to like post", slog.String("error", err.Error()))
 
@@ -132,6 +147,7 @@ func (l *LikeHandler) UnlikePost(w http.ResponseWriter, r *http.Request) {
 // @Accept json
 // @Produce json
 // @Param request body models.LikeRequest true "Like request"
+// @Security BearerAuth
 // @Success 201 {object} customResponse.CustomStatus
 // @Failure 400 {object} customResponse.Error
 // @Failure 500 {object} customResponse.Error
@@ -142,6 +158,16 @@ func (l *LikeHandler) LikePost(w http.ResponseWriter, r *http.Request) {
 	log := l.log.With(slog.String("op", op))
 	log.Info("starting delete post")
 
+	userID, ok := middleware.UserIDFromContext(r.Context())
+	if !ok {
+		log.Error("user id is missing in context")
+
+		render.Status(r, http.StatusUnauthorized)
+		render.JSON(w, r, customResponse.NewError("unauthorized"))
+
+		return
+	}
+
 	var req models.LikeRequest
 	err := render.DecodeJSON(r.Body, &req)
 	if err != nil {
@@ -154,7 +180,9 @@ func (l *LikeHandler) LikePost(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	err = l.likeService.LikePostByID(&req)
+	req.UserID = userID
+
+	err = l.likeService.LikePostByID(r.Context(), &req)
 	if err != nil {
 		log.Error("unable to like post", slog.String("error", err.Error()))
 
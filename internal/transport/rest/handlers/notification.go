@@ -0,0 +1,379 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/go-chi/render"
+	"io"
+	"kirkagram/internal/lib/logger/handlers/customResponse"
+	"kirkagram/internal/lib/pagination"
+	"kirkagram/internal/models"
+	"kirkagram/internal/transport/rest/middleware"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+type Notification interface {
+	GetNotifications(ctx context.Context, userID int, cursor string, limit int) (*models.Page[models.Notification], error)
+	GetUnreadCount(ctx context.Context, userID int) (*models.UnreadCountResponse, error)
+	MarkRead(ctx context.Context, userID int, req models.MarkReadRequest) error
+	GetNotificationsAfter(ctx context.Context, userID int, seq int64, limit int) ([]models.Notification, error)
+	GetLatestSeq(ctx context.Context, userID int) (int64, error)
+}
+
+type NotificationStream interface {
+	Subscribe(userID int) (<-chan struct{}, func())
+}
+
+const (
+	// streamBatch is the number of notifications read from the storage per query of a stream.
+	streamBatch = 100
+	// streamHeartbeat keeps idle streams alive through proxies that close silent connections.
+	streamHeartbeat = 25 * time.Second
+	// streamWriteTimeout disconnects a client that doesn't read, it resumes with Last-Event-ID.
+	streamWriteTimeout = 10 * time.Second
+	// streamRetry is the reconnection delay suggested to the client, in milliseconds.
+	streamRetry = 3000
+)
+
+type NotificationHandler struct {
+	notificationService Notification
+	stream              NotificationStream
+	log                 *slog.Logger
+}
+
+func NewNotificationHandler(notificationService Notification, stream NotificationStream, log *slog.Logger) *NotificationHandler {
+	return &NotificationHandler{
+		notificationService: notificationService,
+		stream:              stream,
+		log:                 log,
+	}
+}
+
+// GetNotifications godoc
+// @Summary Get notifications
+// @Description Get notifications of the authenticated user, the most recent activity first. Repeated events are grouped, e.g. "alice and 12 others liked your post"
+// @Tags notifications
+// @Accept json
+// @Produce json
+// @Param cursor query string false "next_cursor from the previous page"
+// @Param limit query int false "Page size, 20 by default and 100 at most"
+// @Security BearerAuth
+// @Success 200 {object} models.Page[models.Notification]
+// @Failure 400 {object} customResponse.Error
+// @Failure 401 {object} customResponse.Error
+// @Failure 500 {object} customResponse.Error
+// @Router /notifications [get]
+func (n *NotificationHandler) GetNotifications(w http.ResponseWriter, r *http.Request) {
+	const op = "rest.handlers.notification.GetNotifications"
+
+	log := n.log.With(slog.String("op", op))
+	log.Info("starting get notifications")
+
+	userID, ok := middleware.UserIDFromContext(r.Context())
+	if !ok {
+		log.Error("user id is missing in context")
+
+		render.Status(r, http.StatusUnauthorized)
+		render.JSON(w, r, customResponse.NewError("unauthorized"))
+
+		return
+	}
+
+	cursor, limit, err := pageParams(r)
+	if err != nil {
+		log.Error("invalid limit", slog.String("error", err.Error()))
+
+		render.Status(r, http.StatusBadRequest)
+		render.JSON(w, r, customResponse.NewError("limit must be numeric"))
+
+		return
+	}
+
+	page, err := n.notificationService.GetNotifications(r.Context(), userID, cursor, limit)
+	if err != nil {
+		if errors.Is(err, pagination.ErrInvalidCursor) {
+			log.Error("invalid cursor", slog.String("error", err.Error()))
+
+			render.Status(r, http.StatusBadRequest)
+			render.JSON(w, r, customResponse.NewError(pagination.ErrInvalidCursor.Error()))
+
+			return
+		}
+
+		log.Error("unable to get notifications", slog.Int("userID", userID), slog.String("error", err.Error()))
+
+		render.Status(r, http.StatusInternalServerError)
+		render.JSON(w, r, customResponse.NewError(err.Error()))
+
+		return
+	}
+
+	render.Status(r, http.StatusOK)
+	render.JSON(w, r, page)
+}
+
+// GetUnreadCount godoc
+// @Summary Get unread notifications count
+// @Description Get the number of unread notifications of the authenticated user
+// @Tags notifications
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} models.UnreadCountResponse
+// @Failure 401 {object} customResponse.Error
+// @Failure 500 {object} customResponse.Error
+// @Router /notifications/unread-count [get]
+func (n *NotificationHandler) GetUnreadCount(w http.ResponseWriter, r *http.Request) {
+	const op = "rest.handlers.notification.GetUnreadCount"
+
+	log := n.log.With(slog.String("op", op))
+	log.Info("starting get unread count")
+
+	userID, ok := middleware.UserIDFromContext(r.Context())
+	if !ok {
+		log.Error("user id is missing in context")
+
+		render.Status(r, http.StatusUnauthorized)
+		render.JSON(w, r, customResponse.NewError("unauthorized"))
+
+		return
+	}
+
+	count, err := n.notificationService.GetUnreadCount(r.Context(), userID)
+	if err != nil {
+		log.Error("unable to get unread count", slog.Int("userID", userID), slog.String("error", err.Error()))
+
+		render.Status(r, http.StatusInternalServerError)
+		render.JSON(w, r, customResponse.NewError(err.Error()))
+
+		return
+	}
+
+	render.Status(r, http.StatusOK)
+	render.JSON(w, r, count)
+}
+
+// MarkRead godoc
+// @Summary Mark notifications read
+// @Description Mark the listed notifications of the authenticated user read. Without ids all notifications are marked read
+// @Tags notifications
+// @Accept json
+// @Produce json
+// @Param request body models.MarkReadRequest false "Notification IDs"
+// @Security BearerAuth
+// @Success 200 {object} customResponse.CustomStatus
+// @Failure 400 {object} customResponse.Error
+// @Failure 401 {object} customResponse.Error
+// @Failure 500 {object} customResponse.Error
+// @Router /notifications/read [post]
+func (n *NotificationHandler) MarkRead(w http.ResponseWriter, r *http.Request) {
+	const op = "rest.handlers.notification.MarkRead"
+
+	log := n.log.With(slog.String("op", op))
+	log.Info("starting mark notifications read")
+
+	userID, ok := middleware.UserIDFromContext(r.Context())
+	if !ok {
+		log.Error("user id is missing in context")
+
+		render.Status(r, http.StatusUnauthorized)
+		render.JSON(w, r, customResponse.NewError("unauthorized"))
+
+		return
+	}
+
+	var req models.MarkReadRequest
+	err := render.DecodeJSON(r.Body, &req)
+	if err != nil && !errors.Is(err, io.EOF) {
+		log.Error("unable to decode body", slog.String("error", err.Error()))
+
+		render.Status(r, http.StatusBadRequest)
+		render.JSON(w, r, customResponse.NewError(err.Error()))
+
+		return
+	}
+
+	err = n.notificationService.MarkRead(r.Context(), userID, req)
+	if err != nil {
+		log.Error("unable to mark notifications read", slog.Int("userID", userID), slog.String("error", err.Error()))
+
+		render.Status(r, http.StatusInternalServerError)
+		render.JSON(w, r, customResponse.NewError(err.Error()))
+
+		return
+	}
+
+	render.Status(r, http.StatusOK)
+	render.JSON(w, r, customResponse.NewStatus(200))
+}
+
+// Stream godoc
+// @Summary Stream notifications
+// @Description Stream changes of the notifications of the authenticated user as Server-Sent Events. Every event is a "notification" with the notification in data and its seq in id. A notification that was read gets read set, one left without actors, e.g. after an unlike, gets removed set. A reconnecting client sends the last id in the Last-Event-ID header or the last_event_id parameter and receives the changes it missed. Browsers may pass the access token in the access_token parameter
+// @Tags notifications
+// @Produce text/event-stream
+// @Param last_event_id query int false "seq of the last received notification"
+// @Param access_token query string false "Access token, when the Authorization header can't be set"
+// @Security BearerAuth
+// @Success 200 {object} models.Notification
+// @Failure 400 {object} customResponse.Error
+// @Failure 401 {object} customResponse.Error
+// @Failure 500 {object} customResponse.Error
+// @Router /notifications/stream [get]
+func (n *NotificationHandler) Stream(w http.ResponseWriter, r *http.Request) {
+	const op = "rest.handlers.notification.Stream"
+
+	log := n.log.With(slog.String("op", op))
+
+	userID, ok := middleware.UserIDFromContext(r.Context())
+	if !ok {
+		log.Error("user id is missing in context")
+
+		render.Status(r, http.StatusUnauthorized)
+		render.JSON(w, r, customResponse.NewError("unauthorized"))
+
+		return
+	}
+
+	log = log.With(slog.Int("userID", userID))
+
+	// Subscribe before reading the missed changes, so a change made in between isn't lost.
+	wake, unsubscribe := n.stream.Subscribe(userID)
+	defer unsubscribe()
+
+	seq, err := lastEventID(r)
+	if err != nil {
+		log.Error("invalid last event id", slog.String("error", err.Error()))
+
+		render.Status(r, http.StatusBadRequest)
+		render.JSON(w, r, customResponse.NewError("last event id must be numeric"))
+
+		return
+	}
+
+	if seq < 0 {
+		// A new stream starts after the latest change.
+		seq, err = n.notificationService.GetLatestSeq(r.Context(), userID)
+		if err != nil {
+			log.Error("unable to get latest notification", slog.String("error", err.Error()))
+
+			render.Status(r, http.StatusInternalServerError)
+			render.JSON(w, r, customResponse.NewError(err.Error()))
+
+			return
+		}
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+
+	rc := http.NewResponseController(w)
+
+	write := func(format string, args ...any) error {
+		if err := rc.SetWriteDeadline(time.Now().Add(streamWriteTimeout)); err != nil && !errors.Is(err, http.ErrNotSupported) {
+			return err
+		}
+
+		if _, err := fmt.Fprintf(w, format, args...); err != nil {
+			return err
+		}
+
+		return rc.Flush()
+	}
+
+	if err := write("retry: %d\n\n", streamRetry); err != nil {
+		log.Info("stream closed", slog.String("error", err.Error()))
+
+		return
+	}
+
+	log.Info("stream opened", slog.Int64("lastEventID", seq))
+
+	heartbeat := time.NewTicker(streamHeartbeat)
+	defer heartbeat.Stop()
+
+	for {
+		seq, err = n.sendNotifications(r.Context(), write, userID, seq)
+		if err != nil {
+			log.Info("stream closed", slog.String("error", err.Error()))
+
+			return
+		}
+
+		select {
+		case <-r.Context().Done():
+			log.Info("stream closed by client")
+
+			return
+		case _, ok := <-wake:
+			if !ok {
+				log.Info("stream closed by server shutdown")
+
+				return
+			}
+		case <-heartbeat.C:
+			if err := write(": ping\n\n"); err != nil {
+				log.Info("stream closed", slog.String("error", err.Error()))
+
+				return
+			}
+		}
+	}
+}
+
+// sendNotifications writes the notifications changed after seq and returns the seq of the last one sent.
+func (n *NotificationHandler) sendNotifications(ctx context.Context, write func(format string, args ...any) error, userID int, seq int64) (int64, error) {
+	for {
+		notifications, err := n.notificationService.GetNotificationsAfter(ctx, userID, seq, streamBatch)
+		if err != nil {
+			return seq, err
+		}
+
+		for _, notification := range notifications {
+			data, err := json.Marshal(notification)
+			if err != nil {
+				return seq, err
+			}
+
+			if err := write("id: %d\nevent: notification\ndata: %s\n\n", notification.Seq, data); err != nil {
+				return seq, err
+			}
+
+			seq = notification.Seq
+		}
+
+		if len(notifications) < streamBatch {
+			return seq, nil
+		}
+	}
+}
+
+// lastEventID returns the seq a reconnecting stream resumes after, -1 for a new stream.
+func lastEventID(r *http.Request) (int64, error) {
+	value := r.Header.Get("Last-Event-ID")
+	if value == "" {
+		value = r.URL.Query().Get("last_event_id")
+	}
+
+	if value == "" {
+		return -1, nil
+	}
+
+	seq, err := strconv.ParseInt(value, 10, 64)
+	if err != nil {
+		return 0, err
+	}
+	if seq < 0 {
+		return 0, fmt.Errorf("negative last event id %d", seq)
+	}
+
+	return seq, nil
+}
@@ -0,0 +1,26 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+)
+
+// pageParams reads the optional cursor and limit query parameters of a list endpoint.
+func pageParams(r *http.Request) (string, int, error) {
+	limit, err := queryInt(r, "limit")
+	if err != nil {
+		return "", 0, err
+	}
+
+	return r.URL.Query().Get("cursor"), limit, nil
+}
+
+// queryInt parses an optional integer query parameter, returning 0 when it is absent.
+func queryInt(r *http.Request, name string) (int, error) {
+	value := r.URL.Query().Get(name)
+	if value == "" {
+		return 0, nil
+	}
+
+	return strconv.Atoi(value)
+}
@@ -1,6 +1,7 @@
 package handlers
 
 import (
+	"context"
 	"crypto/sha256"
 	"errors"
 	"fmt"
@@ -8,6 +9,8 @@ import (
 	"github.com/go-chi/render"
 	"io"
 	"kirkagram/internal/lib/logger/handlers/customResponse"
+	"kirkagram/internal/service"
+	"kirkagram/internal/transport/rest/middleware"
 	"log/slog"
 	"net/http"
 	"strconv"
@@ -15,25 +18,31 @@ import (
 )
 
 type Photo interface {
-	GetPhoto(key string) ([]byte, error)
-	UploadPhoto(key string, data []byte) error
+	GetPhoto(ctx context.Context, key string) (io.ReadCloser, int64, error)
+	UploadPhoto(ctx context.Context, key string, body io.Reader) error
 }
 
+// multipartMemory is how much of an upload is kept in memory, the rest of the file is spooled to
+// a temporary file and decoded from there.
+const multipartMemory = 1 << 20
+
 type UserForPhoto interface {
-	UploadProfilePic(userID int, filename string) error
+	UploadProfilePic(ctx context.Context, userID int, filename string) error
 }
 
 type PhotoHandler struct {
-	userService  UserForPhoto
-	photoService Photo
-	log          *slog.Logger
+	userService   UserForPhoto
+	photoService  Photo
+	maxUploadSize int64
+	log           *slog.Logger
 }
 
-func NewPhotoHandler(userService UserForPhoto, photoService Photo, log *slog.Logger) *PhotoHandler {
+func NewPhotoHandler(userService UserForPhoto, photoService Photo, maxUploadSize int64, log *slog.Logger) *PhotoHandler {
 	return &PhotoHandler{
-		userService:  userService,
-		photoService: photoService,
-		log:          log,
+		userService:   userService,
+		photoService:  photoService,
+		maxUploadSize: maxUploadSize,
+		log:           log,
 	}
 }
 
@@ -44,9 +53,11 @@ func NewPhotoHandler(userService UserForPhoto, photoService Photo, log *slog.Log
 // @Accept multipart/form-data
 // @Produce json
 // @Param photo formData file true "Photo file"
-// @Param id formData int true "User ID"
+// @Security BearerAuth
 // @Success 200 {object} map[string]string
 // @Failure 400 {object} customResponse.Error
+// @Failure 413 {object} customResponse.Error
+// @Failure 415 {object} customResponse.Error
 // @Failure 500 {object} customResponse.Error
 // @Router /photo [post]
 func (h *PhotoHandler) UploadPhoto(w http.ResponseWriter, r *http.Request) {
@@ -55,13 +66,21 @@ func (h *PhotoHandler) UploadPhoto(w http.ResponseWriter, r *http.Request) {
 	log := h.log.With(slog.String("op", op))
 	log.Info("Get photo URL")
 
-	err := r.ParseMultipartForm(10 << 20)
+	userID, ok := middleware.UserIDFromContext(r.Context())
+	if !ok {
+		log.Error("user id is missing in context")
+
+		render.Status(r, http.StatusUnauthorized)
+		render.JSON(w, r, customResponse.NewError("unauthorized"))
+
+		return
+	}
+
+	err := parseUpload(w, r, h.maxUploadSize)
 	if err != nil {
 		log.Error("Failed to parse multipart form", slog.String("error", err.Error()))
 
-		render.Status(r, http.StatusLengthRequired)
-		originalErr := errors.Unwrap(err)
-		render.JSON(w, r, customResponse.NewError(originalErr.Error()))
+		renderFormError(w, r, err)
 
 		return
 	}
@@ -78,16 +97,6 @@ func (h *PhotoHandler) UploadPhoto(w http.ResponseWriter, r *http.Request) {
 	}
 	defer file.Close()
 
-	fileBytes, err := io.ReadAll(file)
-	if err != nil {
-		log.Error("Failed to read file", slog.String("error", err.Error()))
-
-		render.Status(r, http.StatusInternalServerError)
-		originalErr := errors.Unwrap(err)
-		render.JSON(w, r, customResponse.NewError(originalErr.Error()))
-
-		return
-	}
 	filename := header.Filename
 	currentTime := time.Now()
 	timestampString := currentTime.Format("2006-01-02_15-04-05.000000")
@@ -95,19 +104,17 @@ func (h *PhotoHandler) UploadPhoto(w http.ResponseWriter, r *http.Request) {
 	hash := sha256.Sum256([]byte(filename))
 	filename = fmt.Sprintf("%x", hash[:8])
 
-	userID := r.FormValue("id")
-	num, err := strconv.Atoi(userID)
+	// The photo is processed first, a rejected upload leaves the profile picture as it was.
+	err = h.photoService.UploadPhoto(r.Context(), filename, file)
 	if err != nil {
-		log.Error("Failed to convert user ID to int", slog.String("error", err.Error()))
+		log.Error("Failed to upload file", slog.String("error", err.Error()))
 
-		render.Status(r, http.StatusInternalServerError)
-		originalErr := errors.Unwrap(err)
-		render.JSON(w, r, customResponse.NewError(originalErr.Error()))
+		renderUploadError(w, r, err)
 
 		return
 	}
 
-	err = h.userService.UploadProfilePic(num, filename)
+	err = h.userService.UploadProfilePic(r.Context(), userID, filename)
 	if err != nil {
 		log.Error("Failed to upload file to bd", slog.String("error", err.Error()))
 
@@ -118,17 +125,6 @@ func (h *PhotoHandler) UploadPhoto(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	err = h.photoService.UploadPhoto(filename, fileBytes)
-	if err != nil {
-		log.Error("Failed to upload file", slog.String("error", err.Error()))
-
-		render.Status(r, http.StatusInternalServerError)
-		originalErr := errors.Unwrap(err)
-		render.JSON(w, r, customResponse.NewError(originalErr.Error()))
-
-		return
-	}
-
 	render.Status(r, http.StatusOK)
 	render.JSON(w, r, map[string]string{"filename": filename})
 }
@@ -152,7 +148,7 @@ func (h *PhotoHandler) GetPhotoURL(w http.ResponseWriter, r *http.Request) {
 
 	key := chi.URLParam(r, "key")
 
-	photo, err := h.photoService.GetPhoto(key)
+	photo, size, err := h.photoService.GetPhoto(r.Context(), key)
 	if err != nil {
 		log.Error("Failed to get photo from storage", slog.String("error", err.Error()))
 
@@ -163,18 +159,55 @@ func (h *PhotoHandler) GetPhotoURL(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	defer photo.Close()
+
+	if size >= 0 {
+		w.Header().Set("Content-Length", strconv.FormatInt(size, 10))
+	}
+
+	w.WriteHeader(http.StatusOK)
+
+	// The status is already sent, a failed copy can only be logged.
+	if _, err := io.Copy(w, photo); err != nil {
+		log.Error("Failed to write photo", slog.String("error", err.Error()))
+
+		return
+	}
+
 	log.Info("Get photo URL completed successfully")
+}
 
-	render.Status(r, http.StatusOK)
-	_, err = w.Write(photo)
-	if err != nil {
-		log.Error("Failed to write response", slog.String("error", err.Error()))
+// parseUpload parses the multipart form, the request body is limited to maxSize bytes.
+func parseUpload(w http.ResponseWriter, r *http.Request, maxSize int64) error {
+	r.Body = http.MaxBytesReader(w, r.Body, maxSize)
 
-		render.Status(r, http.StatusInternalServerError)
-		originalErr := errors.Unwrap(err)
-		render.JSON(w, r, customResponse.NewError(originalErr.Error()))
+	return r.ParseMultipartForm(multipartMemory)
+}
+
+// renderFormError responds to a form parseUpload failed to parse.
+func renderFormError(w http.ResponseWriter, r *http.Request, err error) {
+	var maxBytesErr *http.MaxBytesError
+	if errors.As(err, &maxBytesErr) {
+		render.Status(r, http.StatusRequestEntityTooLarge)
+		render.JSON(w, r, customResponse.NewError(fmt.Sprintf("Request body is too large, %d bytes at most", maxBytesErr.Limit)))
 
 		return
 	}
-	render.JSON(w, r, customResponse.NewStatus(200))
+
+	render.Status(r, http.StatusLengthRequired)
+	render.JSON(w, r, customResponse.NewError(err.Error()))
+}
+
+func renderUploadError(w http.ResponseWriter, r *http.Request, err error) {
+	switch {
+	case errors.Is(err, service.ErrUnsupportedImage):
+		render.Status(r, http.StatusUnsupportedMediaType)
+		render.JSON(w, r, customResponse.NewError(service.ErrUnsupportedImage.Error()))
+	case errors.Is(err, service.ErrImageTooLarge):
+		render.Status(r, http.StatusRequestEntityTooLarge)
+		render.JSON(w, r, customResponse.NewError(service.ErrImageTooLarge.Error()))
+	default:
+		render.Status(r, http.StatusInternalServerError)
+		render.JSON(w, r, customResponse.NewError(err.Error()))
+	}
 }
@@ -1,6 +1,7 @@
 package handlers
 
 import (
+	"context"
 	"crypto/sha256"
 	"errors"
 	"fmt"
@@ -8,8 +9,11 @@ import (
 	"github.com/go-chi/render"
 	"io"
 	"kirkagram/internal/lib/logger/handlers/customResponse"
+	"kirkagram/internal/lib/pagination"
 	"kirkagram/internal/models"
+	"kirkagram/internal/service"
 	"kirkagram/internal/storage"
+	"kirkagram/internal/transport/rest/middleware"
 	"log/slog"
 	"net/http"
 	"strconv"
@@ -17,61 +21,76 @@ import (
 )
 
 type PhotoUpl interface {
-	UploadPhoto(key string, data []byte) error
+	UploadPhoto(ctx context.Context, key string, body io.Reader) error
 }
 
 type Post interface {
-	CreatePost(post models.CreatePostRequest) error
-	GetAllPosts() (*[]models.Posts, error)
-	GetPostByID(ID int64) (*models.Posts, error)
-	GetAllPostsByUserID(userID int64) (*[]models.Posts, error)
-	DeletePost(ID int64) error
+	CreatePost(ctx context.Context, post models.CreatePostRequest) error
+	GetAllPosts(ctx context.Context, cursor string, limit int) (*models.Page[models.Posts], error)
+	GetPostByID(ctx context.Context, ID int64) (*models.Posts, error)
+	GetAllPostsByUserID(ctx context.Context, userID int64, cursor string, limit int) (*models.Page[models.Posts], error)
+	DeletePost(ctx context.Context, callerID int, ID int64) error
 }
 
 type PostHandler struct {
-	postService  Post
-	photoService PhotoUpl
-	log          *slog.Logger
+	postService   Post
+	photoService  PhotoUpl
+	maxUploadSize int64
+	log           *slog.Logger
 }
 
-func NewPostHandler(postService Post, photoService PhotoUpl, log *slog.Logger) *PostHandler {
+func NewPostHandler(postService Post, photoService PhotoUpl, maxUploadSize int64, log *slog.Logger) *PostHandler {
 	return &PostHandler{
-		postService:  postService,
-		photoService: photoService,
-		log:          log,
+		postService:   postService,
+		photoService:  photoService,
+		maxUploadSize: maxUploadSize,
+		log:           log,
 	}
 }
 
 // DeletePost godoc
 // @Summary Delete a post
-// @Description Delete a post by user ID
+// @Description Delete a post by ID. Only the author of the post can delete it
 // @Tags posts
 // @Accept json
 // @Produce json
-// @Param userId path int true "User ID"
+// @Param id path int true "Post ID"
+// @Security BearerAuth
 // @Success 200 {object} customResponse.CustomStatus
 // @Failure 400 {object} customResponse.Error
+// @Failure 401 {object} customResponse.Error
+// @Failure 403 {object} customResponse.Error
 // @Failure 404 {object} customResponse.Error
 // @Failure 500 {object} customResponse.Error
-// @Router /post/{userId} [delete]
+// @Router /post/{id} [delete]
 func (p *PostHandler) DeletePost(w http.ResponseWriter, r *http.Request) {
 	const op = "rest.handlers.post.DeletePost"
 
 	log := p.log.With(slog.String("op", op))
 	log.Info("starting delete post")
 
-	userID := chi.URLParam(r, "userId")
+	userID, ok := middleware.UserIDFromContext(r.Context())
+	if !ok {
+		log.Error("user id is missing in context")
 
-	if userID == "" {
-		log.Error("userID is empty")
+		render.Status(r, http.StatusUnauthorized)
+		render.JSON(w, r, customResponse.NewError("unauthorized"))
+
+		return
+	}
+
+	id := chi.URLParam(r, "id")
+
+	if id == "" {
+		log.Error("id is empty")
 
 		render.Status(r, http.StatusBadRequest)
-		render.JSON(w, r, customResponse.NewError("userID is empty"))
+		render.JSON(w, r, customResponse.NewError("id is empty"))
 
 		return
 	}
 
-	num, err := strconv.Atoi(userID)
+	num, err := strconv.Atoi(id)
 	if err != nil {
 		log.Error("error converting id to int")
 
@@ -81,10 +100,19 @@ func (p *PostHandler) DeletePost(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	err = p.postService.DeletePost(int64(num))
+	err = p.postService.DeletePost(r.Context(), userID, int64(num))
 	if err != nil {
+		if errors.Is(err, service.ErrForbidden) {
+			log.Error("user can delete only own posts", slog.Int("callerID", userID), slog.String("id", id))
+
+			render.Status(r, http.StatusForbidden)
+			render.JSON(w, r, customResponse.NewError(service.ErrForbidden.Error()))
+
+			return
+		}
+
 		if errors.Is(err, storage.ErrPostNotFound) {
-			log.Error("error deleting post", slog.String("userID", userID), slog.String("error", err.Error()))
+			log.Error("error deleting post", slog.String("id", id), slog.String("error", err.Error()))
 
 			render.Status(r, http.StatusNotFound)
 			render.JSON(w, r, customResponse.NewError(err.Error()))
@@ -92,7 +120,7 @@ func (p *PostHandler) DeletePost(w http.ResponseWriter, r *http.Request) {
 			return
 		}
 
-		log.Error("error deleting post", slog.String("userID", userID), slog.String("error", err.Error()))
+		log.Error("error deleting post", slog.String("id", id), slog.String("error", err.Error()))
 
 		render.Status(r, http.StatusInternalServerError)
 		render.JSON(w, r, customResponse.NewError(err.Error()))
@@ -111,7 +139,9 @@ func (p *PostHandler) DeletePost(w http.ResponseWriter, r *http.Request) {
 // @Accept json
 // @Produce json
 // @Param userId path int true "User ID"
-// @Success 200 {array} models.Posts
+// @Param cursor query string false "next_cursor from the previous page"
+// @Param limit query int false "Page size, 20 by default and 100 at most"
+// @Success 200 {object} models.Page[models.Posts]
 // @Failure 400 {object} customResponse.Error
 // @Failure 404 {object} customResponse.Error
 // @Failure 500 {object} customResponse.Error
@@ -143,8 +173,27 @@ func (p *PostHandler) GetUserPosts(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	posts, err := p.postService.GetAllPostsByUserID(int64(num))
+	cursor, limit, err := pageParams(r)
+	if err != nil {
+		log.Error("invalid limit", slog.String("error", err.Error()))
+
+		render.Status(r, http.StatusBadRequest)
+		render.JSON(w, r, customResponse.NewError("limit must be numeric"))
+
+		return
+	}
+
+	posts, err := p.postService.GetAllPostsByUserID(r.Context(), int64(num), cursor, limit)
 	if err != nil {
+		if errors.Is(err, pagination.ErrInvalidCursor) {
+			log.Error("invalid cursor", slog.String("error", err.Error()))
+
+			render.Status(r, http.StatusBadRequest)
+			render.JSON(w, r, customResponse.NewError(pagination.ErrInvalidCursor.Error()))
+
+			return
+		}
+
 		if errors.Is(err, storage.ErrPostNotFound) {
 			log.Error("error getting all posts", slog.String("userId", userID), slog.String("error", err.Error()))
 
@@ -207,7 +256,7 @@ func (p *PostHandler) GetPostByID(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	post, err := p.postService.GetPostByID(int64(num))
+	post, err := p.postService.GetPostByID(r.Context(), int64(num))
 	if err != nil {
 		if errors.Is(err, storage.ErrPostNotFound) {
 			log.Error("post not found", slog.String("error", err.Error()), slog.String("op", op))
@@ -236,10 +285,12 @@ func (p *PostHandler) GetPostByID(w http.ResponseWriter, r *http.Request) {
 // @Accept multipart/form-data
 // @Produce json
 // @Param photo formData file true "Photo file"
-// @Param user_id formData int true "User ID"
+// @Security BearerAuth
 // @Param caption formData string true "Post caption"
 // @Success 201 {object} customResponse.CustomStatus
 // @Failure 400 {object} customResponse.Error
+// @Failure 413 {object} customResponse.Error
+// @Failure 415 {object} customResponse.Error
 // @Failure 500 {object} customResponse.Error
 // @Router /post [post]
 func (p *PostHandler) CreatePost(w http.ResponseWriter, r *http.Request) {
@@ -248,17 +299,36 @@ func (p *PostHandler) CreatePost(w http.ResponseWriter, r *http.Request) {
 	log := p.log.With(slog.String("op", op))
 	log.Info("starting creating post")
 
-	err := r.ParseMultipartForm(10 << 20)
+	userID, ok := middleware.UserIDFromContext(r.Context())
+	if !ok {
+		log.Error("user id is missing in context")
+
+		render.Status(r, http.StatusUnauthorized)
+		render.JSON(w, r, customResponse.NewError("unauthorized"))
+
+		return
+	}
+
+	err := parseUpload(w, r, p.maxUploadSize)
 	if err != nil {
 		log.Error("Failed to parse multipart form", slog.String("error", err.Error()))
 
-		render.Status(r, http.StatusLengthRequired)
-		render.JSON(w, r, customResponse.NewError(err.Error()))
+		renderFormError(w, r, err)
 
 		return
 	}
 
 	file, header, err := r.FormFile("photo")
+	if err != nil {
+		log.Error("Failed to get file from form", slog.String("error", err.Error()))
+
+		render.Status(r, http.StatusBadRequest)
+		render.JSON(w, r, customResponse.NewError(err.Error()))
+
+		return
+	}
+	defer file.Close()
+
 	currentTime := time.Now()
 	timestampString := currentTime.Format("2006-01-02_15-04-05.000000")
 	filename := header.Filename
@@ -266,38 +336,27 @@ func (p *PostHandler) CreatePost(w http.ResponseWriter, r *http.Request) {
 	hash := sha256.Sum256([]byte(filename))
 	filename = fmt.Sprintf("%x", hash[:8])
 
-	fileRead, err := io.ReadAll(file)
+	// The photo is processed first, so a rejected upload doesn't leave a post without a photo.
+	err = p.photoService.UploadPhoto(r.Context(), filename, file)
 	if err != nil {
-		log.Error("Failed to read file", slog.String("error", err.Error()))
+		log.Error("Failed to upload file", slog.String("error", err.Error()))
 
-		render.Status(r, http.StatusLengthRequired)
-		render.JSON(w, r, customResponse.NewError(err.Error()))
+		renderUploadError(w, r, err)
 
 		return
 	}
 
-	userID := r.FormValue("user_id")
 	caption := r.FormValue("caption")
 
 	filenameURL := "/api/photo/" + filename
 
-	userIDInt, err := strconv.Atoi(userID)
-	if err != nil {
-		log.Error("error converting user id to int", slog.String("error", err.Error()))
-
-		render.Status(r, http.StatusInternalServerError)
-		render.JSON(w, r, customResponse.NewError(err.Error()))
-
-		return
-	}
-
 	post := models.CreatePostRequest{
-		UserID:   userIDInt,
+		UserID:   userID,
 		Caption:  caption,
 		ImageURL: filenameURL,
 	}
 
-	err = p.postService.CreatePost(post)
+	err = p.postService.CreatePost(r.Context(), post)
 	if err != nil {
 		log.Error("Unable to create post", slog.String("error", err.Error()))
 
@@ -307,16 +366,6 @@ func (p *PostHandler) CreatePost(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	err = p.photoService.UploadPhoto(filename, fileRead)
-	if err != nil {
-		log.Error("Failed to upload file", slog.String("error", err.Error()))
-
-		render.Status(r, http.StatusInternalServerError)
-		render.JSON(w, r, customResponse.NewError(err.Error()))
-
-		return
-	}
-
 	log.Info("finished creation", slog.String("filename", filename))
 
 	render.Status(r, http.StatusCreated)
@@ -325,11 +374,14 @@ func (p *PostHandler) CreatePost(w http.ResponseWriter, r *http.Request) {
 
 // GetAllPosts godoc
 // @Summary Get all posts
-// @Description Get a list of all posts
+// @Description Get a list of all posts, newest first
 // @Tags posts
 // @Accept json
 // @Produce json
-// @Success 200 {array} models.Posts
+// @Param cursor query string false "next_cursor from the previous page"
+// @Param limit query int false "Page size, 20 by default and 100 at most"
+// @Success 200 {object} models.Page[models.Posts]
+// @Failure 400 {object} customResponse.Error
 // @Failure 500 {object} customResponse.Error
 // @Router /post/all [get]
 func (p *PostHandler) GetAllPosts(w http.ResponseWriter, r *http.Request) {
@@ -338,8 +390,27 @@ func (p *PostHandler) GetAllPosts(w http.ResponseWriter, r *http.Request) {
 	log := p.log.With(slog.String("op", op))
 	log.Info("starting getting all posts")
 
-	posts, err := p.postService.GetAllPosts()
+	cursor, limit, err := pageParams(r)
+	if err != nil {
+		log.Error("invalid limit", slog.String("error", err.Error()))
+
+		render.Status(r, http.StatusBadRequest)
+		render.JSON(w, r, customResponse.NewError("limit must be numeric"))
+
+		return
+	}
+
+	posts, err := p.postService.GetAllPosts(r.Context(), cursor, limit)
 	if err != nil {
+		if errors.Is(err, pagination.ErrInvalidCursor) {
+			log.Error("invalid cursor", slog.String("error", err.Error()))
+
+			render.Status(r, http.StatusBadRequest)
+			render.JSON(w, r, customResponse.NewError(pagination.ErrInvalidCursor.Error()))
+
+			return
+		}
+
 		log.Error("Failed to get all posts", slog.String("error", err.Error()))
 
 		render.Status(r, http.StatusInternalServerError)
@@ -6,8 +6,11 @@ import (
 	"github.com/go-chi/chi"
 	"github.com/go-chi/render"
 	"kirkagram/internal/lib/logger/handlers/customResponse"
+	"kirkagram/internal/lib/pagination"
 	"kirkagram/internal/models"
+	"kirkagram/internal/service"
 	"kirkagram/internal/storage"
+	"kirkagram/internal/transport/rest/middleware"
 	"log/slog"
 	"net/http"
 	"strconv"
@@ -15,12 +18,12 @@ import (
 
 type User interface {
 	GetByID(ctx context.Context, ID string) (*models.GetUserResponse, error)
-	Update(ctx context.Context, updateUser models.UpdateUserRequest) error
-	GetAllFollowers(ctx context.Context, userID int) (*[]models.GetAllFollowersResponse, error)
-	GetAllFollowing(ctx context.Context, userID int) (*[]models.GetAllFollowersResponse, error)
-	UploadProfilePic(userID int, filename string) error
-	DeleteUser(ID int64) error
-	RegisterUser(user models.CreateUserRequest) error
+	Update(ctx context.Context, callerID int, updateUser models.UpdateUserRequest) error
+	GetAllFollowers(ctx context.Context, userID int, cursor string, limit int) (*models.Page[models.GetAllFollowersResponse], error)
+	GetAllFollowing(ctx context.Context, userID int, cursor string, limit int) (*models.Page[models.GetAllFollowersResponse], error)
+	UploadProfilePic(ctx context.Context, userID int, filename string) error
+	DeleteUser(ctx context.Context, callerID int, ID int64) error
+	RegisterUser(ctx context.Context, user models.CreateUserRequest) error
 }
 
 type UserHandler struct {
@@ -64,7 +67,7 @@ func (h *UserHandler) Register(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	err = h.userService.RegisterUser(user)
+	err = h.userService.RegisterUser(r.Context(), user)
 	if err != nil {
 		log.Error("register user error", slog.String("error", err.Error()))
 
@@ -86,8 +89,11 @@ func (h *UserHandler) Register(w http.ResponseWriter, r *http.Request) {
 // @Accept json
 // @Produce json
 // @Param id path int true "User ID"
+// @Security BearerAuth
 // @Success 200 {object} customResponse.CustomStatus
 // @Failure 400 {object} customResponse.Error
+// @Failure 401 {object} customResponse.Error
+// @Failure 403 {object} customResponse.Error
 // @Failure 404 {object} customResponse.Error
 // @Failure 500 {object} customResponse.Error
 // @Router /user/{id} [delete]
@@ -99,6 +105,16 @@ func (h *UserHandler) DeleteUser(w http.ResponseWriter, r *http.Request) {
 	log := h.log.With(slog.String("op", op), slog.String("ID", ID))
 	log.Info("start delete user")
 
+	userID, ok := middleware.UserIDFromContext(r.Context())
+	if !ok {
+		log.Error("user id is missing in context")
+
+		render.Status(r, http.StatusUnauthorized)
+		render.JSON(w, r, customResponse.NewError("unauthorized"))
+
+		return
+	}
+
 	if ID == "" {
 		log.Error("id is empty")
 
@@ -118,8 +134,17 @@ func (h *UserHandler) DeleteUser(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	err = h.userService.DeleteUser(int64(num))
+	err = h.userService.DeleteUser(r.Context(), userID, int64(num))
 	if err != nil {
+		if errors.Is(err, service.ErrForbidden) {
+			log.Error("user can delete only own account", slog.Int("callerID", userID))
+
+			render.Status(r, http.StatusForbidden)
+			render.JSON(w, r, customResponse.NewError(service.ErrForbidden.Error()))
+
+			return
+		}
+
 		if errors.Is(err, storage.ErrUserNotFound) {
 			log.Error("user not found")
 
@@ -159,7 +184,6 @@ func (h *UserHandler) GetUser(w http.ResponseWriter, r *http.Request) {
 	log := h.log.With(slog.String("op", op))
 	log.Info("Get user")
 
-	ctx := context.Background()
 	ID := chi.URLParam(r, "id")
 
 	if ID == "" {
@@ -173,7 +197,7 @@ func (h *UserHandler) GetUser(w http.ResponseWriter, r *http.Request) {
 
 	log.Info("Get user by email", slog.String("id", ID))
 
-	user, err := h.userService.GetByID(ctx, ID)
+	user, err := h.userService.GetByID(r.Context(), ID)
 	if err != nil {
 		if errors.Is(err, storage.ErrUserNotFound) {
 			log.Error("Get user by email with error", slog.String("id", ID), slog.String("error", err.Error()))
@@ -207,8 +231,11 @@ func (h *UserHandler) GetUser(w http.ResponseWriter, r *http.Request) {
 // @Accept json
 // @Produce json
 // @Param user body models.UpdateUserRequest true "Updated user information"
+// @Security BearerAuth
 // @Success 200 {object} customResponse.CustomStatus
 // @Failure 400 {object} customResponse.Error
+// @Failure 401 {object} customResponse.Error
+// @Failure 403 {object} customResponse.Error
 // @Failure 404 {object} customResponse.Error
 // @Failure 500 {object} customResponse.Error
 // @Router /user [put]
@@ -218,7 +245,15 @@ func (h *UserHandler) UpdateUser(w http.ResponseWriter, r *http.Request) {
 	log := h.log.With(slog.String("op", op))
 	log.Info("Update user")
 
-	ctx := context.Background()
+	userID, ok := middleware.UserIDFromContext(r.Context())
+	if !ok {
+		log.Error("user id is missing in context")
+
+		render.Status(r, http.StatusUnauthorized)
+		render.JSON(w, r, customResponse.NewError("unauthorized"))
+
+		return
+	}
 
 	var updateUser models.UpdateUserRequest
 	if err := render.DecodeJSON(r.Body, &updateUser); err != nil {
@@ -231,8 +266,17 @@ func (h *UserHandler) UpdateUser(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	err := h.userService.Update(ctx, updateUser)
+	err := h.userService.Update(r.Context(), userID, updateUser)
 	if err != nil {
+		if errors.Is(err, service.ErrForbidden) {
+			log.Error("user can update only own account", slog.Int("callerID", userID))
+
+			render.Status(r, http.StatusForbidden)
+			render.JSON(w, r, customResponse.NewError(service.ErrForbidden.Error()))
+
+			return
+		}
+
 		if errors.Is(err, storage.ErrUserNotFound) {
 			log.Error("Update user with error", slog.String("error", err.Error()))
 
@@ -265,7 +309,9 @@ func (h *UserHandler) UpdateUser(w http.ResponseWriter, r *http.Request) {
 // @Accept json
 // @Produce json
 // @Param userID path int true "User ID"
-// @Success 200 {array} models.GetAllFollowersResponse
+// @Param cursor query string false "next_cursor from the previous page"
+// @Param limit query int false "Page size, 20 by default and 100 at most"
+// @Success 200 {object} models.Page[models.GetAllFollowersResponse]
 // @Failure 400 {object} customResponse.Error
 // @Failure 404 {object} customResponse.Error
 // @Failure 500 {object} customResponse.Error
@@ -276,7 +322,6 @@ func (h *UserHandler) GetAllFollowers(w http.ResponseWriter, r *http.Request) {
 	log := h.log.With(slog.String("op", op))
 	log.Info("Get all followers")
 
-	ctx := context.Background()
 	userID := chi.URLParam(r, "userID")
 
 	if userID == "" {
@@ -299,8 +344,27 @@ func (h *UserHandler) GetAllFollowers(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	followers, err := h.userService.GetAllFollowers(ctx, userIDInt)
+	cursor, limit, err := pageParams(r)
 	if err != nil {
+		log.Error("invalid limit", slog.String("error", err.Error()))
+
+		render.Status(r, http.StatusBadRequest)
+		render.JSON(w, r, customResponse.NewError("limit must be numeric"))
+
+		return
+	}
+
+	followers, err := h.userService.GetAllFollowers(r.Context(), userIDInt, cursor, limit)
+	if err != nil {
+		if errors.Is(err, pagination.ErrInvalidCursor) {
+			log.Error("invalid cursor", slog.String("error", err.Error()))
+
+			render.Status(r, http.StatusBadRequest)
+			render.JSON(w, r, customResponse.NewError(pagination.ErrInvalidCursor.Error()))
+
+			return
+		}
+
 		if errors.Is(err, storage.ErrUserNotFound) {
 			log.Error("Get all followers with error", slog.Int("userID", userIDInt), slog.String("error", err.Error()))
 
@@ -333,7 +397,9 @@ func (h *UserHandler) GetAllFollowers(w http.ResponseWriter, r *http.Request) {
 // @Accept json
 // @Produce json
 // @Param userID path int true "User ID"
-// @Success 200 {array} models.GetAllFollowersResponse
+// @Param cursor query string false "next_cursor from the previous page"
+// @Param limit query int false "Page size, 20 by default and 100 at most"
+// @Success 200 {object} models.Page[models.GetAllFollowersResponse]
 // @Failure 400 {object} customResponse.Error
 // @Failure 404 {object} customResponse.Error
 // @Failure 500 {object} customResponse.Error
@@ -344,7 +410,6 @@ func (h *UserHandler) GetAllFollowing(w http.ResponseWriter, r *http.Request) {
 	log := h.log.With(slog.String("op", op))
 	log.Info("Get all following")
 
-	ctx := context.Background()
 	userID := chi.URLParam(r, "userID")
 
 	if userID == "" {
@@ -367,8 +432,27 @@ func (h *UserHandler) GetAllFollowing(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	followers, err := h.userService.GetAllFollowing(ctx, userIDInt)
+	cursor, limit, err := pageParams(r)
 	if err != nil {
+		log.Error("invalid limit", slog.String("error", err.Error()))
+
+		render.Status(r, http.StatusBadRequest)
+		render.JSON(w, r, customResponse.NewError("limit must be numeric"))
+
+		return
+	}
+
+	followers, err := h.userService.GetAllFollowing(r.Context(), userIDInt, cursor, limit)
+	if err != nil {
+		if errors.Is(err, pagination.ErrInvalidCursor) {
+			log.Error("invalid cursor", slog.String("error", err.Error()))
+
+			render.Status(r, http.StatusBadRequest)
+			render.JSON(w, r, customResponse.NewError(pagination.ErrInvalidCursor.Error()))
+
+			return
+		}
+
 		if errors.Is(err, storage.ErrUserNotFound) {
 			log.Error("Get all following with error", slog.Int("userID", userIDInt), slog.String("error", err.Error()))
 
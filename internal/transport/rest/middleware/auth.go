@@ -0,0 +1,84 @@
+package middleware
+
+import (
+	"context"
+	"github.com/go-chi/render"
+	"kirkagram/internal/lib/jwt"
+	"kirkagram/internal/lib/logger/handlers/customResponse"
+	"log/slog"
+	"net/http"
+	"strings"
+)
+
+type ctxKey string
+
+const userIDKey ctxKey = "userID"
+
+// Auth verifies the bearer access token and stores the caller's user ID in the request context.
+func Auth(secret string, log *slog.Logger) func(next http.Handler) http.Handler {
+	return authenticate(secret, log, bearerToken)
+}
+
+// StreamAuth is Auth for streaming endpoints. Browsers can't set headers on an EventSource,
+// so the access token may also be passed in the access_token query parameter.
+func StreamAuth(secret string, log *slog.Logger) func(next http.Handler) http.Handler {
+	return authenticate(secret, log, func(r *http.Request) string {
+		if token := bearerToken(r); token != "" {
+			return token
+		}
+
+		return r.URL.Query().Get("access_token")
+	})
+}
+
+func authenticate(secret string, log *slog.Logger, tokenFrom func(r *http.Request) string) func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		fn := func(w http.ResponseWriter, r *http.Request) {
+			const op = "rest.middleware.Auth"
+
+			log := log.With(slog.String("op", op))
+
+			tokenString := tokenFrom(r)
+			if tokenString == "" {
+				log.Info("missing bearer token")
+
+				render.Status(r, http.StatusUnauthorized)
+				render.JSON(w, r, customResponse.NewError("missing bearer token"))
+
+				return
+			}
+
+			userID, err := jwt.ParseToken(tokenString, secret)
+			if err != nil {
+				log.Info("invalid access token", slog.String("error", err.Error()))
+
+				render.Status(r, http.StatusUnauthorized)
+				render.JSON(w, r, customResponse.NewError(jwt.ErrInvalidToken.Error()))
+
+				return
+			}
+
+			ctx := context.WithValue(r.Context(), userIDKey, userID)
+
+			next.ServeHTTP(w, r.WithContext(ctx))
+		}
+
+		return http.HandlerFunc(fn)
+	}
+}
+
+func bearerToken(r *http.Request) string {
+	tokenString, found := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if !found {
+		return ""
+	}
+
+	return tokenString
+}
+
+// UserIDFromContext returns the ID of the authenticated caller put into the context by Auth.
+func UserIDFromContext(ctx context.Context) (int, bool) {
+	userID, ok := ctx.Value(userIDKey).(int)
+
+	return userID, ok
+}
@@ -0,0 +1,34 @@
+package middleware
+
+import (
+	"net/http"
+	"time"
+)
+
+// Deadline sets the read and write deadlines of the connection for the request. The server has no
+// read and write timeouts of its own, so every route gets the limits it needs. A zero duration clears
+// the deadline, a long-lived handler then sets its own.
+func Deadline(read, write time.Duration) func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		fn := func(w http.ResponseWriter, r *http.Request) {
+			rc := http.NewResponseController(w)
+			now := time.Now()
+
+			// The errors only report writers that don't support deadlines, such a request runs without them.
+			_ = rc.SetReadDeadline(deadline(now, read))
+			_ = rc.SetWriteDeadline(deadline(now, write))
+
+			next.ServeHTTP(w, r)
+		}
+
+		return http.HandlerFunc(fn)
+	}
+}
+
+func deadline(now time.Time, d time.Duration) time.Time {
+	if d == 0 {
+		return time.Time{}
+	}
+
+	return now.Add(d)
+}
@@ -0,0 +1,9 @@
+-- События для Kafka пишутся в одной транзакции с изменением данных, relay публикует их и удаляет
+CREATE TABLE IF NOT EXISTS "outbox" (
+    id BIGSERIAL PRIMARY KEY,
+    topic VARCHAR(255) NOT NULL,
+    payload BYTEA NOT NULL,
+    attempts INTEGER NOT NULL DEFAULT 0, -- Неудачные попытки публикации
+    last_error TEXT,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS "notification_actor";
+DROP TABLE IF EXISTS "notification";
@@ -0,0 +1,27 @@
+-- Уведомления: одна строка на группу однотипных событий, пока группа не прочитана
+CREATE TABLE IF NOT EXISTS "notification" (
+    id SERIAL PRIMARY KEY,
+    user_id INTEGER NOT NULL,      -- Получатель
+    type VARCHAR(20) NOT NULL,     -- like, follow, comment или reply
+    post_id INTEGER,               -- Пост, к которому относится группа, у подписок NULL
+    read_at TIMESTAMP,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP, -- Время последнего события в группе
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
+    FOREIGN KEY (post_id) REFERENCES post(id) ON DELETE CASCADE
+);
+
+-- Новое событие попадает в непрочитанную группу, после прочтения открывается новая
+CREATE UNIQUE INDEX IF NOT EXISTS notification_unread_group_idx
+    ON notification(user_id, type, COALESCE(post_id, 0)) WHERE read_at IS NULL;
+CREATE INDEX IF NOT EXISTS notification_inbox_idx ON notification(user_id, updated_at DESC, id DESC);
+
+-- Участники группы, каждый пользователь учитывается один раз
+CREATE TABLE IF NOT EXISTS "notification_actor" (
+    notification_id INTEGER NOT NULL,
+    actor_id INTEGER NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (notification_id, actor_id),
+    FOREIGN KEY (notification_id) REFERENCES notification(id) ON DELETE CASCADE,
+    FOREIGN KEY (actor_id) REFERENCES users(id) ON DELETE CASCADE
+);
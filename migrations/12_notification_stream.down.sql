@@ -0,0 +1,5 @@
+DROP INDEX IF EXISTS notification_stream_idx;
+
+ALTER TABLE "notification" DROP COLUMN IF EXISTS seq;
+
+DROP SEQUENCE IF EXISTS notification_seq;
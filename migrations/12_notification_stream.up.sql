@@ -0,0 +1,6 @@
+-- Номер последнего изменения группы уведомлений, по нему клиент продолжает поток после переподключения
+CREATE SEQUENCE IF NOT EXISTS notification_seq;
+
+ALTER TABLE "notification" ADD COLUMN IF NOT EXISTS seq BIGINT NOT NULL DEFAULT nextval('notification_seq');
+
+CREATE INDEX IF NOT EXISTS notification_stream_idx ON notification(user_id, seq);
@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS outbox_pending_idx;
+
+ALTER TABLE "outbox" DROP COLUMN IF EXISTS parked_at;
@@ -0,0 +1,5 @@
+-- Сообщения, которые нельзя опубликовать, откладываются и больше не блокируют очередь.
+-- Чтобы отправить их снова, достаточно сбросить parked_at
+ALTER TABLE "outbox" ADD COLUMN IF NOT EXISTS parked_at TIMESTAMP;
+
+CREATE INDEX IF NOT EXISTS outbox_pending_idx ON "outbox"(id) WHERE parked_at IS NULL;
@@ -0,0 +1,6 @@
+-- Общая последовательность продолжается после seq, выданных счётчиками
+SELECT setval('notification_seq', GREATEST((SELECT MAX(seq) FROM "notification"), 1));
+
+ALTER TABLE "notification" ALTER COLUMN seq SET DEFAULT nextval('notification_seq');
+
+DROP TABLE IF EXISTS "notification_counter";
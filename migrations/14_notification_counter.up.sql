@@ -0,0 +1,14 @@
+-- Счётчик изменений уведомлений каждого пользователя. Строка счётчика заблокирована до конца транзакции,
+-- поэтому изменения одного пользователя фиксируются в порядке seq и поток не пропускает поздний коммит
+CREATE TABLE IF NOT EXISTS "notification_counter" (
+    user_id INTEGER PRIMARY KEY,
+    seq BIGINT NOT NULL DEFAULT 0, -- Последний выданный seq пользователя
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+INSERT INTO "notification_counter" (user_id, seq)
+SELECT user_id, MAX(seq) FROM "notification" GROUP BY user_id
+ON CONFLICT (user_id) DO NOTHING;
+
+-- seq выдаёт счётчик пользователя в той же транзакции
+ALTER TABLE "notification" ALTER COLUMN seq SET DEFAULT 0;
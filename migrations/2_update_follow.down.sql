@@ -1 +1,7 @@
-DROP TABLE IF EXISTS "follow" CASCADE;
\ No newline at end of file
+DROP TABLE IF EXISTS "follow" CASCADE;
+
+ALTER TABLE "users"
+    ADD COLUMN IF NOT EXISTS followers INTEGER[], -- Массив ID подписчиков
+    ADD COLUMN IF NOT EXISTS following INTEGER[]; -- Массив ID пользователей, на которых подписан
+
+ALTER TABLE "users" RENAME TO "user";
@@ -9,8 +9,8 @@ CREATE TABLE IF NOT EXISTS "follow" (
     follower_id INTEGER NOT NULL,
     following_id INTEGER NOT NULL,
     created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
-    FOREIGN KEY (follower_id) REFERENCES "user"(id) ON DELETE CASCADE,
-    FOREIGN KEY (following_id) REFERENCES "user"(id) ON DELETE CASCADE,
+    FOREIGN KEY (follower_id) REFERENCES users(id) ON DELETE CASCADE,
+    FOREIGN KEY (following_id) REFERENCES users(id) ON DELETE CASCADE,
     UNIQUE (follower_id, following_id)
 );
 
@@ -0,0 +1 @@
+DROP TABLE IF EXISTS "refresh_token" CASCADE;
\ No newline at end of file
@@ -0,0 +1,13 @@
+CREATE TABLE IF NOT EXISTS "refresh_token" (
+    id SERIAL PRIMARY KEY,
+    user_id INTEGER NOT NULL,
+    family_id VARCHAR(32) NOT NULL,         -- Все токены, полученные ротацией из одного логина
+    token_hash VARCHAR(64) NOT NULL UNIQUE, -- SHA-256 от токена, сам токен не храним
+    expires_at TIMESTAMP NOT NULL,
+    revoked_at TIMESTAMP,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS refresh_token_family_idx ON refresh_token(family_id);
+CREATE INDEX IF NOT EXISTS refresh_token_user_idx ON refresh_token(user_id);
@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS comment_post_created_idx;
+
+ALTER TABLE "comment"
+    DROP COLUMN IF EXISTS updated_at;
@@ -0,0 +1,4 @@
+ALTER TABLE "comment"
+    ADD COLUMN IF NOT EXISTS updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP;
+
+CREATE INDEX IF NOT EXISTS comment_post_created_idx ON "comment"(post_id, created_at DESC, id DESC);
@@ -0,0 +1,7 @@
+DROP INDEX IF EXISTS comment_parent_created_idx;
+
+DELETE FROM "comment" WHERE parent_id IS NOT NULL OR deleted_at IS NOT NULL;
+
+ALTER TABLE "comment"
+    DROP COLUMN IF EXISTS deleted_at,
+    DROP COLUMN IF EXISTS parent_id;
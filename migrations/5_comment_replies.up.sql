@@ -0,0 +1,5 @@
+ALTER TABLE "comment"
+    ADD COLUMN IF NOT EXISTS parent_id INTEGER REFERENCES "comment"(id) ON DELETE CASCADE, -- NULL у комментариев верхнего уровня
+    ADD COLUMN IF NOT EXISTS deleted_at TIMESTAMP;                                       -- Заполнен у удалённых комментариев, на которые есть ответы
+
+CREATE INDEX IF NOT EXISTS comment_parent_created_idx ON "comment"(parent_id, created_at, id);
@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS like_post_idx;
+DROP INDEX IF EXISTS post_user_created_idx;
@@ -0,0 +1,2 @@
+CREATE INDEX IF NOT EXISTS post_user_created_idx ON "post"(user_id, created_at DESC, id DESC);
+CREATE INDEX IF NOT EXISTS like_post_idx ON "like"(post_id);
@@ -0,0 +1 @@
+DROP TABLE IF EXISTS "feed_entry" CASCADE;
\ No newline at end of file
@@ -0,0 +1,20 @@
+-- Материализованные ленты: запись на каждый пост каждому подписчику автора
+CREATE TABLE IF NOT EXISTS "feed_entry" (
+    user_id INTEGER NOT NULL,   -- Владелец ленты
+    post_id INTEGER NOT NULL,
+    author_id INTEGER NOT NULL,
+    created_at TIMESTAMP NOT NULL, -- Время создания поста, по нему сортируется лента
+    PRIMARY KEY (user_id, post_id),
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
+    FOREIGN KEY (post_id) REFERENCES post(id) ON DELETE CASCADE,
+    FOREIGN KEY (author_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS feed_entry_timeline_idx ON feed_entry(user_id, created_at DESC, post_id DESC);
+CREATE INDEX IF NOT EXISTS feed_entry_author_idx ON feed_entry(user_id, author_id);
+
+INSERT INTO feed_entry (user_id, post_id, author_id, created_at)
+SELECT f.follower_id, p.id, p.user_id, COALESCE(p.created_at, CURRENT_TIMESTAMP)
+FROM follow f
+JOIN post p ON p.user_id = f.following_id
+ON CONFLICT DO NOTHING;
@@ -0,0 +1 @@
+CREATE INDEX IF NOT EXISTS post_created_idx ON "post"(created_at DESC, id DESC);
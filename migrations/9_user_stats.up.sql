@@ -0,0 +1,18 @@
+-- Счётчики пользователя, их поддерживает обработчик событий cmd/produces
+CREATE TABLE IF NOT EXISTS "user_stats" (
+    user_id INTEGER PRIMARY KEY,
+    posts_count INTEGER NOT NULL DEFAULT 0,
+    followers_count INTEGER NOT NULL DEFAULT 0,
+    following_count INTEGER NOT NULL DEFAULT 0,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+INSERT INTO user_stats (user_id, posts_count, followers_count, following_count)
+SELECT
+    u.id,
+    (SELECT COUNT(*) FROM post p WHERE p.user_id = u.id),
+    (SELECT COUNT(*) FROM follow f WHERE f.following_id = u.id),
+    (SELECT COUNT(*) FROM follow f WHERE f.follower_id = u.id)
+FROM users u
+ON CONFLICT DO NOTHING;